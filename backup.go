@@ -0,0 +1,233 @@
+package godevman
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Get backup target file name (<BasePath>/<DevIdent>_<time><ext>) and its timestamp
+func (d *device) backupTarget(ext string) (string, time.Time) {
+	t := time.Now()
+	loc, _ := time.LoadLocation(d.timeZone)
+	t = t.In(loc)
+
+	return d.backupParams.BasePath + "/" + d.backupParams.DevIdent + "_" + t.Format(time_iso8601_sec) + ext, t
+}
+
+// Check backup parameters of device initiated (push) backup. Returns target ip
+func (d *device) backupHost() (string, error) {
+	if d.backupParams == nil {
+		return "", fmt.Errorf("device backup parameters are not defined")
+	}
+
+	host := d.backupParams.TargetIp
+	if host == "" {
+		return "", fmt.Errorf("target ip is not defined")
+	}
+
+	return host, nil
+}
+
+// Get port of embedded receiver. def - port used when receiver is not requested
+func (d *device) backupRecvPort(def string) (string, error) {
+	if d.backupParams.RecvAddr == "" {
+		return def, nil
+	}
+
+	_, port, err := net.SplitHostPort(d.backupParams.RecvAddr)
+	if err != nil {
+		return "", fmt.Errorf("not valid receiver address: %v", err)
+	}
+
+	return port, nil
+}
+
+// Check that embedded receiver uses port def. For devices which can't use other port
+func (d *device) backupRecvDefPort(def string) error {
+	port, err := d.backupRecvPort(def)
+	if err != nil {
+		return err
+	}
+	if port != def {
+		return fmt.Errorf("receiver port %s is not supported, device uses port %s", port, def)
+	}
+
+	return nil
+}
+
+// Get backup target user and password
+func (d *device) backupCred() (string, string) {
+	var user, pass string
+	if len(d.backupParams.Cred) > 0 {
+		user = d.backupParams.Cred[0]
+	}
+	if len(d.backupParams.Cred) > 1 {
+		pass = d.backupParams.Cred[1]
+	}
+
+	return user, pass
+}
+
+// Record result of DoBackup as BackupInfo. Returns err unchanged.
+// host - target ip (empty if backup was fetched from device), file - target file
+func (d *device) backupDone(host, file string, t time.Time, err error) error {
+	info := &BackupInfo{
+		TargetIP:   host,
+		TargetFile: file,
+		Timestamp:  int(t.Unix()),
+	}
+	if err == nil {
+		info.Progress = 100
+		info.Success = true
+	}
+	d.backupParams.info = info
+
+	return err
+}
+
+// Get info of last backup initiated by DoBackup
+func (d *device) backupInfo() (*BackupInfo, error) {
+	if d.backupParams == nil || d.backupParams.info == nil {
+		return nil, fmt.Errorf("no backup info")
+	}
+
+	info := *d.backupParams.info
+	return &info, nil
+}
+
+// Run function on sftp session to device. Uses cli session parameters for authentication.
+func (d *device) sftpSession(p *CliParams, fn func(*sftp.Client) error) error {
+	if p.Telnet {
+		return fmt.Errorf("sftp is not available on telnet session")
+	}
+
+	addr := net.JoinHostPort(d.ip, p.Port)
+	sc, err := ssh.Dial("tcp", addr, d.cliSshConfig(p))
+	if err != nil {
+		return fmt.Errorf("ssh connection to %s failed: %v", addr, err)
+	}
+	defer sc.Close()
+
+	c, err := sftp.NewClient(sc)
+	if err != nil {
+		return fmt.Errorf("start sftp session failed: %v", err)
+	}
+	defer c.Close()
+
+	return fn(c)
+}
+
+// Download files from device via sftp. Uses cli session parameters for authentication.
+func (d *device) sftpFetch(p *CliParams, files ...string) ([]*BackupFile, error) {
+	var out []*BackupFile
+	err := d.sftpSession(p, func(c *sftp.Client) error {
+		for _, name := range files {
+			f, err := c.Open(name)
+			if err != nil {
+				return fmt.Errorf("open %s failed: %v", name, err)
+			}
+
+			data, err := io.ReadAll(io.LimitReader(f, backupMaxSize+1))
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("read %s failed: %v", name, err)
+			}
+			if len(data) > backupMaxSize {
+				return fmt.Errorf("file %s is too large", name)
+			}
+
+			out = append(out, &BackupFile{
+				Name:  name,
+				Proto: "sftp",
+				Src:   d.ip,
+				Time:  time.Now(),
+				Data:  data,
+			})
+		}
+		return nil
+	})
+
+	return out, err
+}
+
+// Upload file to device via sftp. Uses cli session parameters for authentication.
+// name - target file name on device
+func (d *device) sftpPut(p *CliParams, name string, data []byte) error {
+	return d.sftpSession(p, func(c *sftp.Client) error {
+		f, err := c.Create(name)
+		if err != nil {
+			return fmt.Errorf("create %s failed: %v", name, err)
+		}
+
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return fmt.Errorf("write %s failed: %v", name, err)
+		}
+
+		return f.Close()
+	})
+}
+
+// Verify and store backup file fetched from device.
+// File will be written to LocalDir directory if it's set.
+// name - target file name
+func (d *device) backupSave(f *BackupFile, name string) error {
+	f.Name = path.Base(name)
+	if err := d.backupAccept(f); err != nil {
+		return err
+	}
+
+	if d.backupParams.LocalDir == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(d.backupParams.LocalDir, f.Name), f.Data, 0o640); err != nil {
+		return fmt.Errorf("write backup file failed: %v", err)
+	}
+
+	return nil
+}
+
+// Check restore file
+func (d *device) restoreCheck(f *BackupFile) error {
+	if d.backupParams == nil {
+		return fmt.Errorf("device backup parameters are not defined")
+	}
+	if f == nil || f.Name == "" {
+		return fmt.Errorf("restore file is not defined")
+	}
+
+	return nil
+}
+
+// Compare running configuration with restored one.
+// Comparison is skipped if restore file content is not provided.
+// extra - additional volatile line re patterns
+func (d *device) restoreVerify(running string, f *BackupFile, extra ...string) error {
+	if len(f.Data) == 0 {
+		return nil
+	}
+
+	exp, err := NormalizeCfg(string(f.Data), extra...)
+	if err != nil {
+		return err
+	}
+	got, err := NormalizeCfg(running, extra...)
+	if err != nil {
+		return err
+	}
+
+	if diff := UnifiedDiff(SplitLineEnd(exp), SplitLineEnd(got), "restored", "running", 1); diff != "" {
+		return fmt.Errorf("running config differs from restored config:\n%s", diff)
+	}
+
+	return nil
+}
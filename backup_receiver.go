@@ -0,0 +1,841 @@
+package godevman
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Max accepted size of received backup file
+const backupMaxSize = 64 << 20
+
+// Backup file received by embedded receiver
+type BackupFile struct {
+	// File name as sent by device
+	Name string
+	// Transfer protocol ("tftp", "sftp", "scp")
+	Proto string
+	// Device address
+	Src string
+	// Receive time
+	Time time.Time
+	// File content
+	Data []byte
+}
+
+// Storage backend of received backup files
+type BackupStore interface {
+	// Store backup file of device. dev - device identifier (BackupParams.DevIdent)
+	StoreBackup(dev string, f *BackupFile) error
+}
+
+// Store backup file in configuration archive
+func (a *CfgArchive) StoreBackup(dev string, f *BackupFile) error {
+	_, _, err := a.Store(dev, "backup "+f.Name, f.Data)
+	return err
+}
+
+// Get backup file received during last DoBackup. Returns nil if embedded receiver was not used
+func (d *device) BackupFile() *BackupFile {
+	if d.backupParams == nil {
+		return nil
+	}
+	return d.backupParams.file
+}
+
+// Embedded backup receiver
+type backupReceiver struct {
+	// received files
+	files chan *BackupFile
+	// file served for download (restore)
+	serve *BackupFile
+	// device address. Transfers from other sources are refused
+	allow net.IP
+	// stops receiver
+	close func() error
+	// running transfers
+	wg sync.WaitGroup
+}
+
+// Start embedded backup receiver if it's requested in backup parameters.
+// proto - "tftp" or "sftp" (accepts scp uploads too). Returns nil receiver if not requested.
+func (d *device) backupRecvStart(proto string) (*backupReceiver, error) {
+	if d.backupParams != nil {
+		d.backupParams.file = nil
+	}
+
+	return d.backupListen(proto, nil)
+}
+
+// Start embedded server which provides file for restore if it's requested in backup parameters.
+// proto - "tftp" or "sftp" (provides file via scp too). Returns nil server if not requested.
+func (d *device) backupServeStart(proto string, f *BackupFile) (*backupReceiver, error) {
+	return d.backupListen(proto, f)
+}
+
+// Start embedded receiver. serve - file served for download (can be nil)
+func (d *device) backupListen(proto string, serve *BackupFile) (*backupReceiver, error) {
+	p := d.backupParams
+	if p == nil || p.RecvAddr == "" {
+		return nil, nil
+	}
+
+	a, err := net.ResolveIPAddr("ip", d.ip)
+	if err != nil {
+		return nil, fmt.Errorf("start %s receiver failed: not valid device address: %v", proto, err)
+	}
+
+	r := &backupReceiver{files: make(chan *BackupFile, 10), serve: serve, allow: a.IP}
+
+	switch proto {
+	case "tftp":
+		err = r.tftpListen(p.RecvAddr)
+	case "sftp", "scp":
+		err = r.sftpListen(p)
+	default:
+		err = fmt.Errorf("unknown protocol: %s", proto)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("start %s receiver failed: %v", proto, err)
+	}
+
+	return r, nil
+}
+
+// Check if transfer source is device address. Logs refused sources
+func (r *backupReceiver) allowed(src net.IP) bool {
+	if src.Equal(r.allow) {
+		return true
+	}
+
+	log.Printf("warning: backup transfer from %s refused, expected %s\n", src, r.allow)
+	return false
+}
+
+// Get served file if its name matches
+func (r *backupReceiver) served(name string) *BackupFile {
+	if r.serve == nil || path.Base(name) != path.Base(r.serve.Name) {
+		return nil
+	}
+
+	return r.serve
+}
+
+// Wait for backup file, verify it and hand it over to storage backend.
+// name - expected file name (empty - any). Stops receiver.
+func (d *device) backupRecvFinish(r *backupReceiver, name string) error {
+	defer r.stop()
+
+	timeout := time.Duration(d.backupParams.RecvTimeout) * time.Second
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			return fmt.Errorf("backup file %s not received in %v", name, timeout)
+		case f := <-r.files:
+			if name != "" && path.Base(f.Name) != path.Base(name) {
+				log.Printf("warning: unexpected backup file %s from %s ignored\n", f.Name, f.Src)
+				continue
+			}
+			return d.backupAccept(f)
+		}
+	}
+}
+
+// Verify backup file and hand it over to storage backend
+func (d *device) backupAccept(f *BackupFile) error {
+	p := d.backupParams
+
+	minSize := p.MinSize
+	if minSize == 0 {
+		minSize = 1
+	}
+	if len(f.Data) < minSize {
+		return fmt.Errorf("backup file %s is too small: %d bytes", f.Name, len(f.Data))
+	}
+
+	p.file = f
+	if p.Store != nil {
+		if err := p.Store.StoreBackup(p.DevIdent, f); err != nil {
+			return fmt.Errorf("store backup file failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Stop receiver and wait for running transfers
+func (r *backupReceiver) stop() {
+	if err := r.close(); err != nil {
+		log.Printf("warning: backup receiver close error: %v\n", err)
+	}
+	r.wg.Wait()
+}
+
+// Hand over received file. Drops file if nobody is waiting
+func (r *backupReceiver) received(f *BackupFile) {
+	select {
+	case r.files <- f:
+	default:
+		log.Printf("warning: received backup file %s from %s dropped\n", f.Name, f.Src)
+	}
+}
+
+// TFTP opcodes
+const (
+	tftpRrq   = 1
+	tftpWrq   = 2
+	tftpData  = 3
+	tftpAck   = 4
+	tftpError = 5
+)
+
+// Start TFTP receiver (write requests only)
+func (r *backupReceiver) tftpListen(addr string) error {
+	ua, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUDP("udp", ua)
+	if err != nil {
+		return err
+	}
+	r.close = conn.Close
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		buf := make([]byte, 1024)
+		for {
+			n, src, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if n < 4 || !r.allowed(src.IP) {
+				continue
+			}
+
+			switch binary.BigEndian.Uint16(buf) {
+			case tftpWrq:
+				f := strings.Split(string(buf[2:n]), "\x00")
+				if len(f) < 2 {
+					continue
+				}
+				r.wg.Add(1)
+				go func() {
+					defer r.wg.Done()
+					r.tftpReceive(src, f[0], strings.ToLower(f[1]))
+				}()
+			case tftpRrq:
+				f := strings.Split(string(buf[2:n]), "\x00")
+				if len(f) < 2 || r.served(f[0]) == nil {
+					tftpSendErr(conn, src, 1, "file not found")
+					continue
+				}
+				r.wg.Add(1)
+				go func() {
+					defer r.wg.Done()
+					r.tftpSend(src, r.served(f[0]), strings.ToLower(f[1]))
+				}()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Receive TFTP file from client
+func (r *backupReceiver) tftpReceive(src *net.UDPAddr, name, mode string) {
+	// New transfer ID (port) for transfer
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		log.Printf("warning: tftp transfer from %s failed: %v\n", src, err)
+		return
+	}
+	defer conn.Close()
+
+	ack := func(b uint16) []byte {
+		p := make([]byte, 4)
+		binary.BigEndian.PutUint16(p, tftpAck)
+		binary.BigEndian.PutUint16(p[2:], b)
+		return p
+	}
+
+	var data bytes.Buffer
+	var block uint16
+	last := ack(0)
+	retry := 0
+	buf := make([]byte, 1024)
+
+	for {
+		if _, err := conn.WriteToUDP(last, src); err != nil {
+			log.Printf("warning: tftp transfer from %s failed: %v\n", src, err)
+			return
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, a, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && retry < 5 {
+				retry++
+				continue
+			}
+			log.Printf("warning: tftp transfer of %s from %s failed: %v\n", name, src, err)
+			return
+		}
+
+		// Foreign packet
+		if !a.IP.Equal(src.IP) || a.Port != src.Port {
+			tftpSendErr(conn, a, 5, "unknown transfer id")
+			continue
+		}
+		if n < 4 {
+			continue
+		}
+
+		switch binary.BigEndian.Uint16(buf) {
+		case tftpData:
+			// Duplicate blocks are acked again
+			if binary.BigEndian.Uint16(buf[2:]) != block+1 {
+				continue
+			}
+			block++
+			retry = 0
+			data.Write(buf[4:n])
+			last = ack(block)
+
+			if data.Len() > backupMaxSize {
+				tftpSendErr(conn, src, 3, "file too large")
+				return
+			}
+
+			if n-4 < 512 {
+				_, _ = conn.WriteToUDP(last, src)
+
+				b := data.Bytes()
+				if mode == "netascii" {
+					b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+					b = bytes.ReplaceAll(b, []byte("\r\x00"), []byte("\r"))
+				}
+
+				r.received(&BackupFile{
+					Name:  name,
+					Proto: "tftp",
+					Src:   src.IP.String(),
+					Time:  time.Now(),
+					Data:  b,
+				})
+				return
+			}
+		case tftpError:
+			log.Printf("warning: tftp transfer of %s from %s aborted by client: %s\n",
+				name, src, strings.TrimRight(string(buf[4:n]), "\x00"))
+			return
+		}
+	}
+}
+
+// Send TFTP file to client
+func (r *backupReceiver) tftpSend(dst *net.UDPAddr, f *BackupFile, mode string) {
+	// New transfer ID (port) for transfer
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		log.Printf("warning: tftp transfer to %s failed: %v\n", dst, err)
+		return
+	}
+	defer conn.Close()
+
+	data := f.Data
+	if mode == "netascii" {
+		data = bytes.ReplaceAll(data, []byte("\r"), []byte("\r\x00"))
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	}
+	if len(data)/512 > 0xffff {
+		tftpSendErr(conn, dst, 3, "file too large")
+		return
+	}
+
+	buf := make([]byte, 1024)
+	for block := 1; ; block++ {
+		start := (block - 1) * 512
+		end := start + 512
+		if end > len(data) {
+			end = len(data)
+		}
+
+		p := make([]byte, 4, 4+end-start)
+		binary.BigEndian.PutUint16(p, tftpData)
+		binary.BigEndian.PutUint16(p[2:], uint16(block))
+		p = append(p, data[start:end]...)
+
+		acked := false
+		for retry := 0; !acked && retry < 5; retry++ {
+			if _, err := conn.WriteToUDP(p, dst); err != nil {
+				log.Printf("warning: tftp transfer to %s failed: %v\n", dst, err)
+				return
+			}
+
+			_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for {
+				n, a, err := conn.ReadFromUDP(buf)
+				if err != nil {
+					break
+				}
+				if !a.IP.Equal(dst.IP) || a.Port != dst.Port || n < 4 {
+					continue
+				}
+
+				op := binary.BigEndian.Uint16(buf)
+				if op == tftpError {
+					log.Printf("warning: tftp transfer of %s to %s aborted by client: %s\n",
+						f.Name, dst, strings.TrimRight(string(buf[4:n]), "\x00"))
+					return
+				}
+				if op == tftpAck && binary.BigEndian.Uint16(buf[2:]) == uint16(block) {
+					acked = true
+					break
+				}
+			}
+		}
+		if !acked {
+			log.Printf("warning: tftp transfer of %s to %s timed out\n", f.Name, dst)
+			return
+		}
+
+		// Last block is shorter than 512 bytes
+		if end-start < 512 {
+			return
+		}
+	}
+}
+
+// Send TFTP error packet
+func tftpSendErr(conn *net.UDPConn, dst *net.UDPAddr, code uint16, msg string) {
+	p := make([]byte, 4, 5+len(msg))
+	binary.BigEndian.PutUint16(p, tftpError)
+	binary.BigEndian.PutUint16(p[2:], code)
+	p = append(p, msg...)
+	p = append(p, 0)
+	_, _ = conn.WriteToUDP(p, dst)
+}
+
+// Start SFTP receiver. Accepts BackupParams.Cred credentials.
+func (r *backupReceiver) sftpListen(p *BackupParams) error {
+	if len(p.Cred) < 2 {
+		return fmt.Errorf("backup credentials are not defined")
+	}
+
+	var signer ssh.Signer
+	if p.HostKey != nil {
+		s, err := ssh.ParsePrivateKey(p.HostKey)
+		if err != nil {
+			return fmt.Errorf("parse host key failed: %v", err)
+		}
+		signer = s
+	} else {
+		// RSA key for compatibility with older devices
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return fmt.Errorf("generate host key failed: %v", err)
+		}
+		s, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			return fmt.Errorf("generate host key failed: %v", err)
+		}
+		signer = s
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == p.Cred[0] && string(pass) == p.Cred[1] {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %q", c.User())
+		},
+	}
+	config.SetDefaults()
+	// Allow weaker key exchange algorithms and ciphers
+	config.KeyExchanges = append(config.KeyExchanges, "diffie-hellman-group1-sha1", "diffie-hellman-group14-sha1")
+	config.Ciphers = append(config.Ciphers, "aes256-cbc", "aes192-cbc", "aes128-cbc", "3des-cbc")
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", p.RecvAddr)
+	if err != nil {
+		return err
+	}
+
+	// Active connections will be closed on stop
+	var mu sync.Mutex
+	conns := make(map[net.Conn]bool)
+	r.close = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		for c := range conns {
+			c.Close()
+		}
+		return ln.Close()
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			if a, ok := conn.RemoteAddr().(*net.TCPAddr); !ok || !r.allowed(a.IP) {
+				conn.Close()
+				continue
+			}
+
+			mu.Lock()
+			conns[conn] = true
+			mu.Unlock()
+
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				r.sftpServe(conn, config)
+
+				mu.Lock()
+				delete(conns, conn)
+				mu.Unlock()
+			}()
+		}
+	}()
+
+	return nil
+}
+
+// Serve SFTP connection
+func (r *backupReceiver) sftpServe(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Minute))
+
+	sc, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		log.Printf("warning: sftp connection from %s failed: %v\n", conn.RemoteAddr(), err)
+		return
+	}
+	defer sc.Close()
+	go ssh.DiscardRequests(reqs)
+
+	src, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			_ = nc.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		ch, creqs, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+
+		// First accepted request selects session type
+		mode := make(chan string, 1)
+		go func() {
+			selected := false
+			for req := range creqs {
+				ok := false
+				if !selected && (req.Type == "subsystem" || req.Type == "exec") {
+					var v struct{ Val string }
+					if err := ssh.Unmarshal(req.Payload, &v); err == nil {
+						ok = v.Val == "sftp" || scpRe.MatchString(v.Val)
+						if ok {
+							selected = true
+							mode <- v.Val
+						}
+					}
+				}
+				_ = req.Reply(ok, nil)
+			}
+			close(mode)
+		}()
+
+		m, ok := <-mode
+		if !ok {
+			ch.Close()
+			continue
+		}
+
+		if m != "sftp" {
+			c := scpRe.FindStringSubmatch(m)
+			if c[1] == "f" {
+				r.scpSend(ch, src, c[2])
+			} else {
+				r.scpReceive(ch, src, c[2])
+			}
+			continue
+		}
+
+		h := &sftpBackupHandler{r: r, src: src}
+		server := sftp.NewRequestServer(ch, sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h})
+		if err := server.Serve(); err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			log.Printf("warning: sftp session from %s failed: %v\n", src, err)
+		}
+		server.Close()
+	}
+}
+
+// scp upload ("scp -t <target>") or download ("scp -f <source>") command
+var scpRe = regexp.MustCompile(`^scp(?: -[a-zA-Z]+)* -[a-zA-Z]*([tf])[a-zA-Z]*(?: -[a-zA-Z]+)* (.+)$`)
+
+// Send served file via scp protocol (source mode)
+func (r *backupReceiver) scpSend(ch ssh.Channel, src, name string) {
+	defer ch.Close()
+
+	status := func(code uint32) {
+		_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ C uint32 }{code}))
+	}
+
+	f := r.served(name)
+	if f == nil {
+		_, _ = ch.Write([]byte("\x01scp: " + name + ": No such file or directory\n"))
+		status(1)
+		return
+	}
+
+	// Wait for acknowledgment from sink
+	ack := func() bool {
+		b := make([]byte, 1)
+		_, err := io.ReadFull(ch, b)
+		return err == nil && b[0] == 0
+	}
+
+	if !ack() {
+		status(1)
+		return
+	}
+	if _, err := fmt.Fprintf(ch, "C0644 %d %s\n", len(f.Data), path.Base(f.Name)); err != nil || !ack() {
+		status(1)
+		return
+	}
+	if _, err := ch.Write(append(f.Data[:len(f.Data):len(f.Data)], 0)); err != nil || !ack() {
+		log.Printf("warning: scp transfer of %s to %s failed\n", f.Name, src)
+		status(1)
+		return
+	}
+
+	status(0)
+}
+
+// Receive file via scp protocol (sink mode)
+func (r *backupReceiver) scpReceive(ch ssh.Channel, src, target string) {
+	defer ch.Close()
+
+	status := func(code uint32) {
+		_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ C uint32 }{code}))
+	}
+	fail := func(msg string) {
+		_, _ = ch.Write([]byte("\x02" + msg + "\n"))
+		status(1)
+		log.Printf("warning: scp transfer from %s failed: %s\n", src, msg)
+	}
+
+	br := bufio.NewReader(ch)
+	if _, err := ch.Write([]byte{0}); err != nil {
+		return
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			// Client closed session after transfers
+			status(0)
+			return
+		}
+
+		switch line[0] {
+		case 'T', 'D', 'E':
+			// Timestamps and directories are not used
+			_, _ = ch.Write([]byte{0})
+			continue
+		case 'C':
+		default:
+			fail("unsupported scp directive")
+			return
+		}
+
+		// C<mode> <size> <name>
+		f := strings.SplitN(strings.TrimSpace(line), " ", 3)
+		if len(f) < 3 {
+			fail("invalid scp file header")
+			return
+		}
+		size, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil || size < 0 || size > backupMaxSize {
+			fail("invalid file size")
+			return
+		}
+		_, _ = ch.Write([]byte{0})
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			fail(err.Error())
+			return
+		}
+		// Transfer end mark
+		if _, err := br.ReadByte(); err != nil {
+			fail(err.Error())
+			return
+		}
+		_, _ = ch.Write([]byte{0})
+
+		// Target may be directory
+		name := target
+		if path.Ext(target) == "" {
+			name = path.Join(target, f[2])
+		}
+
+		r.received(&BackupFile{
+			Name:  name,
+			Proto: "scp",
+			Src:   src,
+			Time:  time.Now(),
+			Data:  data,
+		})
+	}
+}
+
+// SFTP handler which accepts uploads only
+type sftpBackupHandler struct {
+	r   *backupReceiver
+	src string
+}
+
+func (h *sftpBackupHandler) Fileread(req *sftp.Request) (io.ReaderAt, error) {
+	if f := h.r.served(req.Filepath); f != nil {
+		return bytes.NewReader(f.Data), nil
+	}
+	return nil, sftp.ErrSSHFxPermissionDenied
+}
+
+func (h *sftpBackupHandler) Filewrite(req *sftp.Request) (io.WriterAt, error) {
+	return &sftpBackupFile{h: h, name: req.Filepath}, nil
+}
+
+func (h *sftpBackupHandler) Filecmd(req *sftp.Request) error {
+	switch req.Method {
+	case "Setstat", "Mkdir":
+		return nil
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *sftpBackupHandler) Filelist(req *sftp.Request) (sftp.ListerAt, error) {
+	switch req.Method {
+	case "Stat", "Lstat":
+		if f := h.r.served(req.Filepath); f != nil {
+			return sftpLister{sftpFileInfo{name: path.Base(f.Name), size: int64(len(f.Data))}}, nil
+		}
+		// Files are not stored, directories always exist
+		if path.Ext(req.Filepath) != "" {
+			return nil, os.ErrNotExist
+		}
+		return sftpLister{sftpFileInfo{name: path.Base(req.Filepath), dir: true}}, nil
+	case "List":
+		return sftpLister{}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// Uploaded file kept in memory
+type sftpBackupFile struct {
+	mu   sync.Mutex
+	h    *sftpBackupHandler
+	name string
+	buf  []byte
+}
+
+func (f *sftpBackupFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	end := off + int64(len(p))
+	if end > backupMaxSize {
+		return 0, fmt.Errorf("file too large")
+	}
+	if end > int64(len(f.buf)) {
+		f.buf = append(f.buf, make([]byte, end-int64(len(f.buf)))...)
+	}
+
+	return copy(f.buf[off:], p), nil
+}
+
+// Upload is complete
+func (f *sftpBackupFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.h.r.received(&BackupFile{
+		Name:  f.name,
+		Proto: "sftp",
+		Src:   f.h.src,
+		Time:  time.Now(),
+		Data:  f.buf,
+	})
+
+	return nil
+}
+
+// SFTP directory listing
+type sftpLister []os.FileInfo
+
+func (l sftpLister) ListAt(fi []os.FileInfo, off int64) (int, error) {
+	if off >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := copy(fi, l[off:])
+	if n+int(off) >= len(l) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// SFTP file info
+type sftpFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi sftpFileInfo) Name() string { return fi.name }
+func (fi sftpFileInfo) Size() int64  { return fi.size }
+func (fi sftpFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0o755
+	}
+	return 0o644
+}
+func (fi sftpFileInfo) ModTime() time.Time { return time.Now() }
+func (fi sftpFileInfo) IsDir() bool        { return fi.dir }
+func (fi sftpFileInfo) Sys() interface{}   { return nil }
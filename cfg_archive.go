@@ -0,0 +1,260 @@
+package godevman
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration archive in content addressed directory.
+// Layout:
+// <dir>/objects/<sha256> - configuration content
+// <dir>/devices/<device>.log - device history. Line format: <unix time>\t<sha256>\t<size>\t<source>
+type CfgArchive struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// Archived configuration version
+type CfgVersion struct {
+	// Device identifier
+	Dev string
+	// sha256 of configuration
+	Hash string
+	// Source of configuration (fe. "running", "backup")
+	Source string
+	// Store time
+	Time time.Time
+	// Size of configuration (bytes)
+	Size int
+}
+
+// Configuration change
+type CfgChange struct {
+	*CfgVersion
+	// Unified diff from previous version
+	Diff string
+}
+
+// Initialize configuration archive in directory. Directory will be created if missing.
+func NewCfgArchive(dir string) (*CfgArchive, error) {
+	for _, d := range []string{"objects", "devices"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o750); err != nil {
+			return nil, fmt.Errorf("create archive directory failed: %v", err)
+		}
+	}
+
+	return &CfgArchive{dir: dir}, nil
+}
+
+// Returns device history file path
+func (a *CfgArchive) devPath(dev string) string {
+	name := regexp.MustCompile(`[^\w.-]`).ReplaceAllString(dev, "_")
+	return filepath.Join(a.dir, "devices", name+".log")
+}
+
+// Store device configuration. Unchanged configuration (same as latest version) will be not stored.
+// dev - device identifier, src - configuration source.
+// Returns latest version and true if new version was stored.
+func (a *CfgArchive) Store(dev, src string, cfg []byte) (*CfgVersion, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sum := sha256.Sum256(cfg)
+	hash := hex.EncodeToString(sum[:])
+
+	hist, err := a.history(dev)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(hist) > 0 && hist[len(hist)-1].Hash == hash {
+		return hist[len(hist)-1], false, nil
+	}
+
+	obj := filepath.Join(a.dir, "objects", hash)
+	if _, err := os.Stat(obj); os.IsNotExist(err) {
+		// Write via temporary file to avoid partial objects
+		tmp := obj + ".tmp"
+		if err := os.WriteFile(tmp, cfg, 0o640); err != nil {
+			return nil, false, fmt.Errorf("write config object failed: %v", err)
+		}
+		if err := os.Rename(tmp, obj); err != nil {
+			return nil, false, fmt.Errorf("write config object failed: %v", err)
+		}
+	}
+
+	v := &CfgVersion{
+		Dev:    dev,
+		Hash:   hash,
+		Source: src,
+		Time:   time.Now(),
+		Size:   len(cfg),
+	}
+
+	f, err := os.OpenFile(a.devPath(dev), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, false, fmt.Errorf("open device history failed: %v", err)
+	}
+	defer f.Close()
+
+	src = strings.NewReplacer("\t", " ", "\n", " ").Replace(src)
+	_, err = fmt.Fprintf(f, "%d\t%s\t%d\t%s\n", v.Time.Unix(), hash, v.Size, src)
+	if err != nil {
+		return nil, false, fmt.Errorf("write device history failed: %v", err)
+	}
+
+	return v, true, nil
+}
+
+// Get device configuration versions history (oldest first)
+func (a *CfgArchive) History(dev string) ([]*CfgVersion, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.history(dev)
+}
+
+func (a *CfgArchive) history(dev string) ([]*CfgVersion, error) {
+	var out []*CfgVersion
+
+	f, err := os.Open(a.devPath(dev))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return out, fmt.Errorf("open device history failed: %v", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		p := strings.SplitN(s.Text(), "\t", 4)
+		if len(p) < 4 {
+			continue
+		}
+
+		ts, err := strconv.ParseInt(p[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		size, _ := strconv.Atoi(p[2])
+
+		out = append(out, &CfgVersion{
+			Dev:    dev,
+			Hash:   p[1],
+			Source: p[3],
+			Time:   time.Unix(ts, 0),
+			Size:   size,
+		})
+	}
+
+	if err := s.Err(); err != nil {
+		return out, fmt.Errorf("read device history failed: %v", err)
+	}
+
+	return out, nil
+}
+
+// Get latest configuration version of device. Returns nil if device has no versions
+func (a *CfgArchive) Latest(dev string) (*CfgVersion, error) {
+	hist, err := a.History(dev)
+	if err != nil || len(hist) == 0 {
+		return nil, err
+	}
+
+	return hist[len(hist)-1], nil
+}
+
+// Get configuration content by hash
+func (a *CfgArchive) Get(hash string) ([]byte, error) {
+	if !regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString(hash) {
+		return nil, fmt.Errorf("not valid config hash: %s", hash)
+	}
+
+	b, err := os.ReadFile(filepath.Join(a.dir, "objects", hash))
+	if err != nil {
+		return nil, fmt.Errorf("read config object failed: %v", err)
+	}
+
+	return b, nil
+}
+
+// Get unified diff between two configuration versions.
+// Empty from hash means empty configuration.
+func (a *CfgArchive) Diff(from, to string) (string, error) {
+	var fc []byte
+	fn := "/dev/null"
+	if from != "" {
+		fn = from
+		b, err := a.Get(from)
+		if err != nil {
+			return "", err
+		}
+		fc = b
+	}
+
+	tc, err := a.Get(to)
+	if err != nil {
+		return "", err
+	}
+
+	split := func(b []byte) []string {
+		if len(b) == 0 {
+			return nil
+		}
+		return strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	}
+
+	return UnifiedDiff(split(fc), split(tc), fn, to, 3), nil
+}
+
+// Get unified diffs of device configuration changes (oldest first).
+// n - number of latest changes (0 - all)
+func (a *CfgArchive) Changes(dev string, n int) ([]*CfgChange, error) {
+	var out []*CfgChange
+
+	hist, err := a.History(dev)
+	if err != nil {
+		return out, err
+	}
+
+	start := 0
+	if n > 0 && len(hist) > n {
+		start = len(hist) - n
+	}
+
+	for i := start; i < len(hist); i++ {
+		var prev string
+		if i > 0 {
+			prev = hist[i-1].Hash
+		}
+
+		d, err := a.Diff(prev, hist[i].Hash)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, &CfgChange{CfgVersion: hist[i], Diff: d})
+	}
+
+	return out, nil
+}
+
+// Read running configuration of device and store it in archive.
+// dev - device identifier. Returns latest version and true if new version was stored.
+func (a *CfgArchive) StoreRunning(d DevConfReader, dev string) (*CfgVersion, bool, error) {
+	cfg, err := d.RuningCfg()
+	if err != nil {
+		return nil, false, fmt.Errorf("get running config failed: %v", err)
+	}
+
+	return a.Store(dev, "running", []byte(cfg))
+}
@@ -0,0 +1,68 @@
+package godevman
+
+import "fmt"
+
+// Apply configuration lines in transaction.
+// confirm - decides commit or rollback according to configuration diff. Commits if nil.
+// Returns configuration diff.
+func CfgTransaction(d DevConfigurator, lines []string, confirm func(string) bool) (string, error) {
+	if err := d.CfgOpen(); err != nil {
+		return "", fmt.Errorf("open configuration failed: %v", err)
+	}
+
+	if err := d.CfgApply(lines); err != nil {
+		return "", fmt.Errorf("apply configuration failed: %v", err)
+	}
+
+	diff, err := d.CfgDiff()
+	if err != nil {
+		err2 := d.CfgRollback()
+		if err2 != nil {
+			err = fmt.Errorf("%v; rollback error: %v", err, err2)
+		}
+		return diff, fmt.Errorf("get configuration diff failed: %v", err)
+	}
+
+	if confirm != nil && !confirm(diff) {
+		return diff, d.CfgRollback()
+	}
+
+	if err := d.CfgCommit(); err != nil {
+		return diff, fmt.Errorf("commit configuration failed: %v", err)
+	}
+
+	return diff, nil
+}
+
+// Returns error if configuration transaction is not open
+func (d *device) cfgChkOpen() error {
+	if d.cliSession == nil || !d.cliSession.cfgTx || !d.cliSession.ownedBy(d) {
+		return fmt.Errorf("configuration transaction is not open")
+	}
+	if d.cliSession.client == nil {
+		return d.cfgClose(fmt.Errorf("configuration transaction session lost"))
+	}
+	return nil
+}
+
+// Roll back configuration changes after error. Returns combined error
+func (d *device) cfgAbort(err error, rollback func() error) error {
+	if err2 := rollback(); err2 != nil {
+		return fmt.Errorf("%v; rollback error: %v", err, err2)
+	}
+	return fmt.Errorf("%v; changes rolled back", err)
+}
+
+// Close configuration transaction and persistent cli session. Returns combined error
+func (d *device) cfgClose(err error) error {
+	d.cliSession.cfgTx = false
+	d.cliSession.cfgBase = nil
+
+	if err2 := d.CloseCli(); err2 != nil {
+		if err == nil {
+			return err2
+		}
+		return fmt.Errorf("%v; session close error: %v", err, err2)
+	}
+	return err
+}
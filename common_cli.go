@@ -44,34 +44,14 @@ func (d *device) cliPrepare() (*CliParams, error) {
 	return &params, nil
 }
 
-// Create and store cli expect client and update d.cliSession.params
-func (d *device) startCli(p *CliParams) error {
-	if d.cliSession.client != nil {
-		return nil
-	}
-
-	// store sessions parameters
-	d.cliSession.params = p
-
-	// setup connection related vars
-	addr := fmt.Sprintf("%s:%s", d.ip, p.Port)
-	if p.Telnet {
-		addr = fmt.Sprintf("%s %s", d.ip, p.Port)
-	}
-
+// Create ssh client configuration from cli session parameters
+func (d *device) cliSshConfig(p *CliParams) *ssh.ClientConfig {
 	user := p.Cred[0]
 	pass := ""
 	if len(p.Cred) > 1 {
 		pass = p.Cred[1]
 	}
 
-	timeOut := time.Duration(p.Timeout) * time.Second
-
-	var verbose bool
-	if d.debug > 0 {
-		verbose = true
-	}
-
 	// Allow weaker key exchange algorithms
 	var config ssh.Config
 	config.SetDefaults()
@@ -111,9 +91,42 @@ func (d *device) startCli(p *CliParams) error {
 		User:            user,
 		Auth:            []ssh.AuthMethod{auth},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         timeOut,
+		Timeout:         time.Duration(p.Timeout) * time.Second,
+	}
+
+	return cconf
+}
+
+// Create and store cli expect client and update d.cliSession.params
+func (d *device) startCli(p *CliParams) error {
+	if d.cliSession.client != nil {
+		return nil
+	}
+
+	// store sessions parameters
+	d.cliSession.params = p
+
+	// setup connection related vars
+	addr := fmt.Sprintf("%s:%s", d.ip, p.Port)
+	if p.Telnet {
+		addr = fmt.Sprintf("%s %s", d.ip, p.Port)
+	}
+
+	user := p.Cred[0]
+	pass := ""
+	if len(p.Cred) > 1 {
+		pass = p.Cred[1]
+	}
+
+	timeOut := time.Duration(p.Timeout) * time.Second
+
+	var verbose bool
+	if d.debug > 0 {
+		verbose = true
 	}
 
+	cconf := d.cliSshConfig(p)
+
 	// Create expecter
 	sshExpecter := func() (*expect.GExpect, error) {
 		sshClt, err := ssh.Dial("tcp", addr, cconf)
@@ -209,7 +222,15 @@ func (d *device) startCli(p *CliParams) error {
 // Execute cli commands. Returns all sent, received data as string slice
 // c - cli commands, e - check for command errors
 func (d *device) cliCmds(c []string, f bool) ([]string, error) {
-	var output []string
+	res, err := d.cliCmdsRes(c, f)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+// c - cli commands, e - check for command errors,
+// extra - additional interactive prompt rules (checked before session rules)
+func (d *device) cliCmdsRes(c []string, f bool, extra ...CliInteract) ([]*CliCmdResult, error) {
+	var output []*CliCmdResult
 	e := d.cliSession.client
 	if e == nil {
 		return output, fmt.Errorf("active cli session not found")
@@ -218,26 +239,37 @@ func (d *device) cliCmds(c []string, f bool) ([]string, error) {
 	pRe := regexp.MustCompile(d.cliSession.params.PromptRe)
 	eRe := regexp.MustCompile(d.cliSession.params.ErrRe)
 
+	d.cliSession.lastUsed = time.Now()
+
 	cnt := len(c)
-	for _, cmd := range c {
+	for i, cmd := range c {
 		cnt--
-		output = append(output, cmd)
+		r := &CliCmdResult{Cmd: cmd}
+		output = append(output, r)
+		start := time.Now()
+
 		err := e.Send(cmd + d.cliSession.params.LineEnd)
+		// Reconnect broken persistent session before first command
+		if err != nil && i == 0 && d.cliReconnect() == nil {
+			e = d.cliSession.client
+			err = e.Send(cmd + d.cliSession.params.LineEnd)
+		}
 		if err != nil {
 			return output, fmt.Errorf("send(%q) failed: %v", cmd, err)
 		}
 
 		// Dont expect specific prompt after last cmd
-		if cnt == 0 {
+		// Persistent session needs prompt to be consumed
+		if cnt == 0 && !d.cliSession.persistent {
 			pRe = regexp.MustCompile(`(?m).*$`)
 		}
-		out, _, err := e.Expect(pRe, -1)
+		out, m, err := d.cliExpect(pRe, extra...)
 		out = strings.TrimPrefix(out, cmd+d.cliSession.params.LineEnd)
-		output = append(output, out)
+		r.setOutput(out, m, cnt > 0 || d.cliSession.persistent, start)
 
 		// Check for errors if requested
 		if f {
-			if eRe.Match([]byte(out)) {
+			if r.ErrMatch = eRe.FindString(out); r.ErrMatch != "" {
 				return output, fmt.Errorf("cli command exec error: %s", out)
 			}
 		}
@@ -250,10 +282,165 @@ func (d *device) cliCmds(c []string, f bool) ([]string, error) {
 	return output, nil
 }
 
+// Expect prompt and respond to interactive prompts according to session parameters.
+// Returns all received data, prompt match and error
+func (d *device) cliExpect(pRe *regexp.Regexp, extra ...CliInteract) (string, []string, error) {
+	e := d.cliSession.client
+	p := d.cliSession.params
+
+	rules := append(extra[:len(extra):len(extra)], p.Interact...)
+	if len(rules) == 0 {
+		return e.Expect(pRe, -1)
+	}
+
+	var cases []expect.Caser
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Re)
+		if err != nil {
+			return "", nil, fmt.Errorf("not valid interactive prompt re %q: %v", r.Re, err)
+		}
+		cases = append(cases, &expect.Case{R: re, T: expect.OK()})
+	}
+	cases = append(cases, &expect.Case{R: pRe, T: expect.OK()})
+
+	var output string
+	// Limit responses to avoid endless loop on misbehaving device
+	for n := 0; n < 1000; n++ {
+		out, m, i, err := e.ExpectSwitchCase(cases, -1)
+		output += out
+		if err != nil || i < 0 || i == len(rules) {
+			return output, m, err
+		}
+
+		r := rules[i]
+		resp := r.Send
+		if r.Pass {
+			resp = ""
+			if len(p.Cred) > 1 {
+				resp = p.Cred[1]
+			}
+		}
+		if !r.NoLineEnd {
+			resp += p.LineEnd
+		}
+
+		if err := e.Send(resp); err != nil {
+			return output, m, fmt.Errorf("send interactive response failed: %v", err)
+		}
+	}
+
+	return output, nil, fmt.Errorf("too many interactive prompts")
+}
+
+// Append session close command to commands unless session is persistent
+func (d *device) cliExit(cmds []string, exit string) []string {
+	if d.cliSession != nil && d.cliSession.persistent {
+		return cmds
+	}
+
+	return append(cmds[:len(cmds):len(cmds)], exit)
+}
+
+// Check that device is reachable via new cli session. Session of device object is not used.
+// p - session parameters, cmds - commands to run (must end with session close command)
+func (d *device) cliProbe(p *CliParams, cmds []string) error {
+	probe := &device{ip: d.ip, debug: d.debug, cliSession: new(cliSess)}
+	if err := probe.startCli(p); err != nil {
+		return err
+	}
+
+	_, err := probe.cliCmdsRes(cmds, true)
+	if err2 := probe.closeCli(); err2 != nil && err == nil {
+		err = err2
+	}
+
+	return err
+}
+
+// Get configuration via cli. Returns normalized output of commands.
+// run - RunCmdsStructured of device, cmds - configuration show commands,
+// exit - session close command (not used on persistent session)
+func (d *device) cliConfig(run func([]string, *CliCmdOpts) ([]*CliCmdResult, error), cmds []string, exit string) (string, error) {
+	res, err := run(d.cliExit(cmds, exit), nil)
+	if err != nil {
+		return "", fmt.Errorf("cli command error: %v", err)
+	}
+
+	var out []string
+	for _, r := range res[:len(cmds)] {
+		out = append(out, r.Output)
+	}
+
+	cfg := strings.TrimSpace(strings.Join(out, "\n"))
+	if cfg == "" {
+		return "", fmt.Errorf("no configuration in %q output", cmds)
+	}
+
+	return NormalizeCfg(cfg)
+}
+
+// Set result output fields
+// m - expect match, p - prompt was expected, start - command send time
+func (r *CliCmdResult) setOutput(out string, m []string, p bool, start time.Time) {
+	r.Duration = time.Since(start)
+	r.Raw = out
+	r.received = true
+	r.Output = CliCleanOutput(out, r.Cmd)
+
+	if p && len(m) > 0 {
+		r.Prompt = strings.TrimSpace(m[0])
+		// Remove prompt line
+		if i := strings.LastIndex(r.Output, "\n"); i >= 0 {
+			r.Output = r.Output[:i]
+		} else {
+			r.Output = ""
+		}
+	}
+}
+
+// Returns all sent, received data of cli commands results as string slice
+func CliTranscript(res []*CliCmdResult) []string {
+	var output []string
+	for _, r := range res {
+		output = append(output, r.Cmd)
+		if r.received {
+			output = append(output, r.Raw)
+		}
+	}
+
+	return output
+}
+
+// Remove command echo, pager artefacts and ANSI escape sequences from cli output
+func CliCleanOutput(out, cmd string) string {
+	// ANSI escape sequences
+	out = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|[()][0-9A-B]|[=>78DEM])`).ReplaceAllString(out, "")
+	// Pager prompts and cleanup of those (spaces and backspaces)
+	pager := `(?i)[ \t]*(<?-+ ?\(?more\b[^\r\n]*?-+>?|press any key[^\r\n\x08]*|q ?= ?quit[^\r\n\x08]*)[ \x08]*`
+	out = regexp.MustCompile(`(?m)^`+pager+`\r?\n`).ReplaceAllString(out, "")
+	out = regexp.MustCompile(pager).ReplaceAllString(out, "")
+	out = regexp.MustCompile(`[^\n]\x08`).ReplaceAllString(out, "")
+	out = strings.ReplaceAll(out, "\x08", "")
+	// Line ends
+	out = strings.ReplaceAll(out, "\r\n", "\n")
+	out = regexp.MustCompile(`[^\n]*\r`).ReplaceAllString(out, "")
+	// Command echo
+	if cmd != "" {
+		if i := strings.Index(out, "\n"); i >= 0 && strings.TrimSpace(out[:i]) == strings.TrimSpace(cmd) {
+			out = out[i+1:]
+		} else if strings.TrimSpace(out) == strings.TrimSpace(cmd) {
+			out = ""
+		}
+	}
+
+	return strings.TrimRight(out, " \n")
+}
+
 // Close cli expect client
+// Persistent session will be not closed (see CloseCli)
 func (d *device) closeCli() error {
 	e := d.cliSession.client
-	if e == nil {
+	if e == nil || d.cliSession.persistent {
 		return nil
 	}
 
@@ -269,3 +456,137 @@ func (d *device) closeCli() error {
 	d.cliSession.client = nil
 	return nil
 }
+
+// Make cli session persistent and start it.
+// start - device type specific session start function
+func (d *device) openCli(p *CliParams, start func(*CliParams) error) error {
+	s := d.cliSession
+
+	if s.persistent {
+		return nil
+	}
+
+	err := start(p)
+	if err != nil {
+		return err
+	}
+
+	s.persistent = true
+	s.owner = d
+	s.lastUsed = time.Now()
+	s.start = func() error {
+		return start(p)
+	}
+
+	if p.Keepalive > 0 || p.IdleTimeout > 0 {
+		s.stop = make(chan struct{})
+		go d.cliKeepalive(s.stop)
+	}
+
+	return nil
+}
+
+// Close persistent cli session. RunCmds will use one-shot sessions afterwards.
+func (d *device) CloseCli() error {
+	s := d.cliSession
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.owner != nil && s.owner != d {
+		s.mu.Unlock()
+		return fmt.Errorf("persistent cli session is held by other device object")
+	}
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+
+	// Release lease kept by owner of pooled session
+	release := s.owner == d && s.lease != nil
+	s.owner = nil
+	s.persistent = false
+	s.start = nil
+
+	err := d.closeCli()
+	s.unlock()
+
+	if release {
+		<-s.lease
+	}
+
+	return err
+}
+
+// Drop broken persistent cli session and start new one
+func (d *device) cliReconnect() error {
+	s := d.cliSession
+	if !s.persistent || s.start == nil {
+		return fmt.Errorf("not persistent cli session")
+	}
+	// Configuration transaction state is lost with session
+	if s.cfgTx {
+		return fmt.Errorf("configuration transaction is open")
+	}
+
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+
+	return s.start()
+}
+
+// Keep persistent cli session alive and close it after idle timeout.
+// Closed or broken session will be reopened on next use.
+func (d *device) cliKeepalive(stop chan struct{}) {
+	s := d.cliSession
+	ka := time.Duration(s.params.Keepalive) * time.Second
+	idle := time.Duration(s.params.IdleTimeout) * time.Second
+	lastKa := time.Now()
+
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			// Session is in use
+			if !s.mu.TryLock() {
+				continue
+			}
+
+			switch {
+			case s.client == nil:
+			case idle > 0 && time.Since(s.lastUsed) > idle && !s.cfgTx:
+				for _, cmd := range s.params.DisconnectCmds {
+					if err := s.client.Send(cmd + s.params.LineEnd); err != nil {
+						break
+					}
+				}
+				s.client.Close()
+				s.client = nil
+				s.dropSlot()
+			case ka > 0 && time.Since(lastKa) > ka && time.Since(s.lastUsed) > ka:
+				lastKa = time.Now()
+				re := regexp.MustCompile(s.params.PromptRe)
+				err := s.client.Send(s.params.LineEnd)
+				if err == nil {
+					_, _, err = s.client.Expect(re, -1)
+				}
+				if err != nil {
+					log.Printf("warning: cli session keepalive to %s failed: %v\n", d.ip, err)
+					s.client.Close()
+					s.client = nil
+					s.dropSlot()
+				}
+			}
+
+			s.mu.Unlock()
+		}
+	}
+}
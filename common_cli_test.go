@@ -0,0 +1,69 @@
+package godevman
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCliCleanOutput(t *testing.T) {
+	bs := func(n int) string { return strings.Repeat("\x08", n) }
+
+	tests := []struct {
+		name string
+		out  string
+		cmd  string
+		want string
+	}{
+		{
+			name: "plain with echo",
+			out:  "show version\r\nVersion 1.0\r\n",
+			cmd:  "show version",
+			want: "Version 1.0",
+		},
+		{
+			name: "only echo",
+			out:  "conf t\r\n",
+			cmd:  "conf t",
+			want: "",
+		},
+		{
+			name: "cisco more",
+			out: "show running-config\r\nhostname sw1\r\n --More-- " + bs(10) + strings.Repeat(" ", 10) + bs(10) +
+				"interface Gi0/1\r\n --More-- " + bs(10) + strings.Repeat(" ", 10) + bs(10) + "end\r\n",
+			cmd:  "show running-config",
+			want: "hostname sw1\ninterface Gi0/1\nend",
+		},
+		{
+			name: "juniper more percent",
+			out: "show configuration\r\nsystem {\r\n---(more 45%)---\r" + strings.Repeat(" ", 16) + "\r" +
+				"    host-name r1;\r\n---(more 100%)---\r" + strings.Repeat(" ", 17) + "\r}\r\n",
+			cmd:  "show configuration",
+			want: "system {\n    host-name r1;\n}",
+		},
+		{
+			name: "press any key with backspace cleanup",
+			out: "show interfaces\r\nPort 1  Up\r\nPress any key to continue (Q to quit)" + bs(37) +
+				strings.Repeat(" ", 37) + bs(37) + "Port 2  Down\r\n",
+			cmd:  "show interfaces",
+			want: "Port 1  Up\nPort 2  Down",
+		},
+		{
+			name: "ansi escapes",
+			out:  "\x1b[2J\x1b[H\x1b[?25l\x1b[1mPort\x1b[0m  \x1b[32mUp\x1b[0m\r\n\x1b[K\x1b(BDone\x1b[?25h\r\n",
+			want: "Port  Up\nDone",
+		},
+		{
+			name: "mid line backspaces",
+			out:  "abcx\x08d\r\n",
+			want: "abcd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CliCleanOutput(tt.out, tt.cmd); got != tt.want {
+				t.Errorf("CliCleanOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
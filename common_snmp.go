@@ -93,7 +93,7 @@ func (sd *snmpCommon) IfNumber() (int64, error) {
 // Get info from .iso.org.dod.internet.mgmt.mib-2.interfaces.ifTable and .iso.org.dod.internet.mgmt.mib-2.ifMIB.ifMIBObjects.ifXTable
 // Valid targets values: "All", "AllNoIfx", "Descr", "Name", "Alias", "Type", "Mtu", "Speed", "Mac",
 // "Admin", "Oper", "Last", "InOctets", "InUcast", "InMcast", "InBcast", "InDiscards", "InErrors",
-// "OutOctets", "OutUcast", "OutMcast", "OutBcast", "OutDiscards", "OutErrors"
+// "OutOctets", "OutUcast", "OutMcast", "OutBcast", "OutDiscards", "OutErrors", "Discont"
 func (sd *snmpCommon) IfInfo(targets []string, idx ...string) (map[string]*IfInfo, error) {
 	out := make(map[string]*IfInfo)
 	iftable := ".1.3.6.1.2.1.2.2.1."
@@ -109,7 +109,7 @@ func (sd *snmpCommon) IfInfo(targets []string, idx ...string) (map[string]*IfInf
 	ifxOids := []string{
 		ifxtable + "1", ifxtable + "2", ifxtable + "3", ifxtable + "4", ifxtable + "5", ifxtable + "6",
 		ifxtable + "7", ifxtable + "8", ifxtable + "9", ifxtable + "10", ifxtable + "11", ifxtable + "12",
-		ifxtable + "13", ifxtable + "15", ifxtable + "18",
+		ifxtable + "13", ifxtable + "15", ifxtable + "18", ifxtable + "19",
 	}
 
 	for _, t := range targets {
@@ -169,6 +169,8 @@ func (sd *snmpCommon) IfInfo(targets []string, idx ...string) (map[string]*IfInf
 			oids = append(oids, iftable+"19")
 		case "OutErrors":
 			oids = append(oids, iftable+"20")
+		case "Discont":
+			oids = append(oids, ifxtable+"19")
 		}
 	}
 
@@ -256,6 +258,8 @@ func (sd *snmpCommon) IfInfo(targets []string, idx ...string) (map[string]*IfInf
 				i := mapEntry(o, ifxtable+"6.")
 				out[i].InOctets.Value = d.Counter64
 				out[i].InOctets.IsSet = true
+				out[i].Hc.Value = true
+				out[i].Hc.IsSet = true
 			case strings.Contains(o, iftable+"10."):
 				i := mapEntry(o, iftable+"10.")
 				if out[i].InOctets.IsSet {
@@ -294,6 +298,8 @@ func (sd *snmpCommon) IfInfo(targets []string, idx ...string) (map[string]*IfInf
 				i := mapEntry(o, ifxtable+"10.")
 				out[i].OutOctets.Value = d.Counter64
 				out[i].OutOctets.IsSet = true
+				out[i].Hc.Value = true
+				out[i].Hc.IsSet = true
 			case strings.Contains(o, iftable+"16."):
 				i := mapEntry(o, iftable+"16.")
 				if out[i].OutOctets.IsSet {
@@ -328,6 +334,10 @@ func (sd *snmpCommon) IfInfo(targets []string, idx ...string) (map[string]*IfInf
 				i := mapEntry(o, iftable+"20.")
 				out[i].OutErrors.Value = d.Counter32
 				out[i].OutErrors.IsSet = true
+			case strings.Contains(o, ifxtable+"19."):
+				i := mapEntry(o, ifxtable+"19.")
+				out[i].DiscontTime.Value = d.TimeTicks
+				out[i].DiscontTime.IsSet = true
 			}
 		}
 	}
@@ -917,6 +927,245 @@ func (sd *snmpCommon) SetIfAlias(set map[string]string) error {
 	return nil
 }
 
+// Dot1Q VLAN static table columns
+const (
+	d1qVlanStaticName      = ".1.3.6.1.2.1.17.7.1.4.3.1.1."
+	d1qVlanStaticEgress    = ".1.3.6.1.2.1.17.7.1.4.3.1.2."
+	d1qVlanStaticUntagged  = ".1.3.6.1.2.1.17.7.1.4.3.1.4."
+	d1qVlanStaticRowStatus = ".1.3.6.1.2.1.17.7.1.4.3.1.5."
+)
+
+// Create VLAN using .iso.org.dod.internet.mgmt.mib-2.dot1dBridge.qBridgeMIB.qBridgeMIBObjects.dot1qVlan.dot1qVlanStaticTable
+func (sd *snmpCommon) AddVlan(vid int, name string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := vlanAddCheck(cur, vid); err != nil {
+		return err
+	}
+
+	v := strconv.Itoa(vid)
+	pdus := []snmphelper.SetPDU{
+		{
+			// createAndGo
+			Oid:   d1qVlanStaticRowStatus + v,
+			Vtype: "Integer",
+			Value: 4,
+		},
+		{
+			Oid:   d1qVlanStaticName + v,
+			Vtype: "OctetString",
+			Value: name,
+		},
+	}
+
+	r, err := sd.snmpSession.Set(pdus)
+	if err != nil {
+		return err
+	}
+
+	// DEBUG
+	if sd.debug > 0 {
+		fmt.Printf("AddVlan result: %# v\n", pretty.Formatter(r))
+	}
+
+	return nil
+}
+
+// Remove VLAN from .iso.org.dod.internet.mgmt.mib-2.dot1dBridge.qBridgeMIB.qBridgeMIBObjects.dot1qVlan.dot1qVlanStaticTable
+func (sd *snmpCommon) DelVlan(vid int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanDelCheck(cur, vid); err != nil {
+		return err
+	}
+
+	pdus := []snmphelper.SetPDU{
+		{
+			// destroy
+			Oid:   d1qVlanStaticRowStatus + strconv.Itoa(vid),
+			Vtype: "Integer",
+			Value: 6,
+		},
+	}
+
+	r, err := sd.snmpSession.Set(pdus)
+	if err != nil {
+		return err
+	}
+
+	// DEBUG
+	if sd.debug > 0 {
+		fmt.Printf("DelVlan result: %# v\n", pretty.Formatter(r))
+	}
+
+	return nil
+}
+
+// Set VLAN name in .iso.org.dod.internet.mgmt.mib-2.dot1dBridge.qBridgeMIB.qBridgeMIBObjects.dot1qVlan.dot1qVlanStaticTable
+func (sd *snmpCommon) RenameVlan(vid int, name string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := vlanExists(cur, vid); err != nil {
+		return err
+	}
+
+	pdus := []snmphelper.SetPDU{
+		{
+			Oid:   d1qVlanStaticName + strconv.Itoa(vid),
+			Vtype: "OctetString",
+			Value: name,
+		},
+	}
+
+	r, err := sd.snmpSession.Set(pdus)
+	if err != nil {
+		return err
+	}
+
+	// DEBUG
+	if sd.debug > 0 {
+		fmt.Printf("RenameVlan result: %# v\n", pretty.Formatter(r))
+	}
+
+	return nil
+}
+
+// Returns ifindex to bridgeport map
+func (sd *snmpCommon) ifIdx2BrPort() (map[string]int, error) {
+	out := make(map[string]int)
+
+	r, err := sd.BrPort2IfIdx()
+	if err != nil {
+		return out, err
+	}
+
+	for bp, i := range r {
+		if n, err := strconv.Atoi(bp); err == nil {
+			out[strconv.Itoa(i)] = n
+		}
+	}
+
+	return out, nil
+}
+
+// Set VLAN port membership in .iso.org.dod.internet.mgmt.mib-2.dot1dBridge.qBridgeMIB.qBridgeMIBObjects.dot1qVlan.dot1qVlanStaticTable
+// set - map of ifIndexes and their membership modes (tagged|untagged|none)
+func (sd *snmpCommon) SetVlanPorts(vid int, set map[string]string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPortsCheck(cur, vid, set, nil); err != nil {
+		return err
+	}
+
+	brPort, err := sd.ifIdx2BrPort()
+	if err != nil {
+		return err
+	}
+
+	v := strconv.Itoa(vid)
+	eOid, uOid := d1qVlanStaticEgress+v, d1qVlanStaticUntagged+v
+	r, err := sd.snmpSession.Get([]string{eOid, uOid})
+	if err != nil {
+		return err
+	}
+	egress, untagged := []byte(r[eOid].OctetString), []byte(r[uOid].OctetString)
+
+	for i, m := range set {
+		p, ok := brPort[i]
+		if !ok {
+			return fmt.Errorf("interface with ifindex %s is not bridge port", i)
+		}
+
+		egress = bitMapSet(egress, p, m != VlanPortNone)
+		untagged = bitMapSet(untagged, p, m == VlanPortUntagged)
+	}
+
+	// Keep port lists of equal length
+	for len(untagged) < len(egress) {
+		untagged = append(untagged, 0)
+	}
+	for len(egress) < len(untagged) {
+		egress = append(egress, 0)
+	}
+
+	pdus := []snmphelper.SetPDU{
+		{
+			Oid:   eOid,
+			Vtype: "OctetString",
+			Value: string(egress),
+		},
+		{
+			Oid:   uOid,
+			Vtype: "OctetString",
+			Value: string(untagged),
+		},
+	}
+
+	r, err = sd.snmpSession.Set(pdus)
+	if err != nil {
+		return err
+	}
+
+	// DEBUG
+	if sd.debug > 0 {
+		fmt.Printf("SetVlanPorts result: %# v\n", pretty.Formatter(r))
+	}
+
+	return nil
+}
+
+// Set port VLAN ids in .iso.org.dod.internet.mgmt.mib-2.dot1dBridge.qBridgeMIB.qBridgeMIBObjects.dot1qVlan.dot1qPortVlanTable
+// set - map of ifIndexes and their VLAN ids
+func (sd *snmpCommon) SetPvid(set map[string]int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPvidCheck(cur, set); err != nil {
+		return err
+	}
+
+	brPort, err := sd.ifIdx2BrPort()
+	if err != nil {
+		return err
+	}
+
+	pdus := []snmphelper.SetPDU{}
+	for i, vid := range set {
+		p, ok := brPort[i]
+		if !ok {
+			return fmt.Errorf("interface with ifindex %s is not bridge port", i)
+		}
+
+		pdu := snmphelper.SetPDU{
+			Oid:   ".1.3.6.1.2.1.17.7.1.4.5.1.1." + strconv.Itoa(p),
+			Vtype: "Gauge32",
+			Value: uint32(vid),
+		}
+		pdus = append(pdus, pdu)
+	}
+
+	r, err := sd.snmpSession.Set(pdus)
+	if err != nil {
+		return err
+	}
+
+	// DEBUG
+	if sd.debug > 0 {
+		fmt.Printf("SetPvid result: %# v\n", pretty.Formatter(r))
+	}
+
+	return nil
+}
+
 // Set Device sysName
 func (sd *snmpCommon) SetSysName(v string) error {
 	pdus := []snmphelper.SetPDU{
@@ -54,6 +54,8 @@ func (d *device) webClient(headers map[string][]string) (*http.Client, error) {
 // Make http Get request and return byte slice of body.
 // Argument string should contain request parameters.
 func (d *device) WebApiGet(params string) ([]byte, error) {
+	defer d.webSession.serialize()()
+
 	client := d.webSession.client
 	if d.webSession.client == nil {
 		// setup client
@@ -1,6 +1,12 @@
 package godevman
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Adds Ceragon specific SNMP functionality to snmpCommon type
 type deviceCeragon struct {
@@ -14,8 +20,166 @@ func (sd *deviceCeragon) SwVersion() (string, error) {
 	return r[oid].OctetString, err
 }
 
+// Get info from MWRM-RADIO-MIB (radio tables are indexed by radio ifIndex)
+// Returns radio link info. Map keys are radio ifdescriptions
+func (sd *deviceCeragon) RlInfo() (map[string]*RlRadioIfInfo, error) {
+	out := make(map[string]*RlRadioIfInfo)
+
+	radioTable := ".1.3.6.1.4.1.2281.10.5.1.1."  // genEquipRadioStatusTable
+	mrmcTable := ".1.3.6.1.4.1.2281.10.7.4.1.1." // genEquipRadioMRMCStatusTable
+	pm15Table := ".1.3.6.1.4.1.2281.10.5.6.1.1." // genEquipRadioPmG82615minTable (<ifIndex>.<interval>)
+	pm24Table := ".1.3.6.1.4.1.2281.10.5.7.1.1." // genEquipRadioPmG82624hrTable (<ifIndex>.<interval>)
+
+	r, err := sd.walkCols(map[string]string{
+		"rsl":        radioTable + "2",
+		"tsl":        radioTable + "3",
+		"mse":        radioTable + "4",
+		"modulation": mrmcTable + "4",
+		"capacity":   mrmcTable + "7",
+	})
+	if err != nil {
+		return out, err
+	}
+
+	pmCols := func(t string) map[string]string {
+		return map[string]string{"es": t + "3", "ses": t + "4", "uas": t + "5", "bbe": t + "6"}
+	}
+	pm15, err := sd.walkCols(pmCols(pm15Table))
+	if err != nil {
+		return out, err
+	}
+	pm24, err := sd.walkCols(pmCols(pm24Table))
+	if err != nil {
+		return out, err
+	}
+	g15, g24 := g826Intervals(pm15), g826Intervals(pm24)
+
+	idxs := make([]string, 0, len(r["rsl"]))
+	for idx := range r["rsl"] {
+		idxs = append(idxs, idx)
+	}
+	if len(idxs) == 0 {
+		return out, nil
+	}
+
+	ifs, err := sd.IfInfo([]string{"Descr", "Name", "Admin", "Oper"}, idxs...)
+	if err != nil {
+		return out, err
+	}
+
+	for _, idx := range idxs {
+		i, rf := rlRadio(idx, ifs[idx])
+
+		if v, ok := r["rsl"][idx]; ok {
+			rf.PowerIn = ValF64{Value: float64(v.Integer), IsSet: true}
+		}
+		if v, ok := r["tsl"][idx]; ok {
+			rf.PowerOut = ValF64{Value: float64(v.Integer), IsSet: true}
+		}
+		// MSE (0.01 dB) is negative SNR
+		if v, ok := r["mse"][idx]; ok && v.Integer < 0 {
+			rf.Snr = ValF64{Value: float64(-v.Integer) / 100, IsSet: true}
+		}
+		if v, ok := r["modulation"][idx]; ok {
+			rf.Modulation = ValString{Value: strings.TrimSpace(v.OctetString), IsSet: true}
+		}
+		// Tx bitrate (kbit/s)
+		if v, ok := r["capacity"][idx]; ok {
+			if c, ok := snmpRawUint(v.Raw); ok {
+				rf.TxCapacity = ValInt{Value: int(c) * 1000, IsSet: true}
+			}
+		}
+
+		i.Perf15m, i.Perf24h = g15[idx], g24[idx]
+		if len(i.Perf15m) > 0 && i.Perf15m[0].Interval.Value == 0 {
+			c := i.Perf15m[0]
+			i.Es, i.Ses, i.Bbe, i.Uas = c.Es, c.Ses, c.Bbe, c.Uas
+		}
+
+		out[i.Descr.Value] = i
+	}
+
+	return out, nil
+}
+
+// Get info from MWRM-RADIO-MIB remote radio table
+// Returns far end radio info. Map keys are local radio ifdescriptions
+func (sd *deviceCeragon) RlNbrInfo() (map[string]*RlRadioFeIfInfo, error) {
+	out := make(map[string]*RlRadioFeIfInfo)
+
+	remoteTable := ".1.3.6.1.4.1.2281.10.5.2.1." // genEquipRadioRemoteTable
+
+	r, err := sd.walkCols(map[string]string{
+		"ip":  remoteTable + "2",
+		"rsl": remoteTable + "3",
+		"tsl": remoteTable + "4",
+	})
+	if err != nil {
+		return out, err
+	}
+
+	idxs := make([]string, 0, len(r["ip"]))
+	for idx := range r["ip"] {
+		idxs = append(idxs, idx)
+	}
+	if len(idxs) == 0 {
+		return out, nil
+	}
+
+	ifs, err := sd.IfInfo([]string{"Descr"}, idxs...)
+	if err != nil {
+		return out, err
+	}
+
+	for _, idx := range idxs {
+		fe := new(RlRadioFeIfInfo)
+
+		if n, err := strconv.Atoi(idx); err == nil {
+			fe.IfIdx = ValInt{Value: n, IsSet: true}
+		}
+		if v := r["ip"][idx]; v.IPAddress != "" && v.IPAddress != "0.0.0.0" {
+			fe.Ip = ValString{Value: v.IPAddress, IsSet: true}
+		}
+		if v, ok := r["rsl"][idx]; ok {
+			fe.PowerIn = ValF64{Value: float64(v.Integer), IsSet: true}
+		}
+		if v, ok := r["tsl"][idx]; ok {
+			fe.PowerOut = ValF64{Value: float64(v.Integer), IsSet: true}
+		}
+
+		key := idx
+		if i, ok := ifs[idx]; ok && i.Descr.IsSet {
+			key = i.Descr.Value
+		}
+		out[key] = fe
+	}
+
+	return out, nil
+}
+
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceCeragon) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceCeragon) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceCeragon) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -25,12 +189,17 @@ func (sd *deviceCeragon) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -46,3 +215,79 @@ func (sd *deviceCeragon) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 
 	return out, nil
 }
+
+// Get running config
+func (sd *deviceCeragon) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"show running-config"}, "exit")
+}
+
+// Export configuration backup to sftp server.
+// Device chooses file name, it will be placed to BackupParams.BasePath directory.
+func (sd *deviceCeragon) DoBackup() error {
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+	user, pass := sd.backupCred()
+	_, t := sd.backupTarget("")
+
+	dir := sd.backupParams.BasePath
+	if dir == "" {
+		dir = "/"
+	}
+
+	if err := sd.backupRecvDefPort("22"); err != nil {
+		return sd.backupDone(host, dir, t, err)
+	}
+
+	// Start embedded receiver if requested
+	rcv, err := sd.backupRecvStart("sftp")
+	if err != nil {
+		return sd.backupDone(host, dir, t, err)
+	}
+
+	cmds := []string{
+		"platform configuration channel server set ip-address " + host + " directory " + dir +
+			" username " + user + " password " + pass + " protocol sftp",
+		"platform configuration configuration-file add backup-file-number 1",
+		"platform configuration configuration-file export backup-file-number 1",
+	}
+	_, err = sd.RunCmdsStructured(sd.cliExit(cmds, "exit"), &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		if rcv != nil {
+			rcv.stop()
+		}
+		return sd.backupDone(host, dir, t, fmt.Errorf("cli error: %v", err))
+	}
+
+	if rcv != nil {
+		return sd.backupDone(host, dir, t, sd.backupRecvFinish(rcv, ""))
+	}
+
+	// Wait for export result
+	stRe := regexp.MustCompile(`(?i)\b(success|fail)`)
+	cmds = sd.cliExit([]string{"platform configuration configuration-file-status show"}, "exit")
+	for i := 0; i < 20; i++ {
+		time.Sleep(3 * time.Second)
+		res, err := sd.RunCmdsStructured(cmds, &CliCmdOpts{ChkErr: true})
+		if err != nil {
+			return sd.backupDone(host, dir, t, fmt.Errorf("cli error: %v", err))
+		}
+
+		switch st := stRe.FindStringSubmatch(res[0].Output); {
+		case st == nil:
+			continue
+		case strings.ToLower(st[1]) == "success":
+			return sd.backupDone(host, dir, t, nil)
+		default:
+			return sd.backupDone(host, dir, t, fmt.Errorf("configuration export failed: %s", res[0].Output))
+		}
+	}
+
+	return sd.backupDone(host, dir, t, fmt.Errorf("no confirm for configuration export success"))
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceCeragon) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
+}
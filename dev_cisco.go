@@ -3,6 +3,8 @@ package godevman
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/aretaja/snmphelper"
@@ -355,12 +357,38 @@ func (sd *deviceCisco) cliPrepare() (*CliParams, error) {
 			"terminal width 132",
 		}
 	}
+	if sd.cliSession.params.Interact == nil {
+		params.Interact = []CliInteract{
+			{Re: `--More--\s*$`, Send: " ", NoLineEnd: true},
+		}
+	}
 
 	return params, nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceCisco) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceCisco) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceCisco) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -370,12 +398,17 @@ func (sd *deviceCisco) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -391,3 +424,616 @@ func (sd *deviceCisco) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 
 	return out, nil
 }
+
+// Get active alarms from .iso.org.dod.internet.private.enterprises.cisco.ciscoMgmt.ciscoEntityAlarmMIB tree
+func (sd *deviceCisco) Alarms() ([]*AlarmInfo, error) {
+	var out []*AlarmInfo
+
+	oids := map[string]string{
+		"descrVendor": ".1.3.6.1.4.1.9.9.138.1.1.1.1.2",
+		"alarmList":   ".1.3.6.1.4.1.9.9.138.1.2.5.1.3",
+		"descrSev":    ".1.3.6.1.4.1.9.9.138.1.1.2.1.2",
+		"descrText":   ".1.3.6.1.4.1.9.9.138.1.1.2.1.3",
+		"histType":    ".1.3.6.1.4.1.9.9.138.1.3.3.1.2",
+		"histEntIdx":  ".1.3.6.1.4.1.9.9.138.1.3.3.1.3",
+		"histAlType":  ".1.3.6.1.4.1.9.9.138.1.3.3.1.4",
+		"histTime":    ".1.3.6.1.4.1.9.9.138.1.3.3.1.6",
+		"entPhysType": ".1.3.6.1.2.1.47.1.1.1.1.3",
+		"entPhysName": ".1.3.6.1.2.1.47.1.1.1.1.7",
+	}
+
+	severity := map[int64]string{
+		1: "critical",
+		2: "major",
+		3: "minor",
+		4: "warning",
+	}
+
+	r := make(map[string]snmphelper.SnmpOut)
+	for _, n := range []string{"descrVendor", "alarmList", "descrSev", "descrText"} {
+		res, err := sd.snmpSession.Walk(oids[n], true, true)
+		if err != nil && sd.handleErr(err) {
+			return out, err
+		}
+		r[n] = res
+	}
+
+	// Collect asserted alarm types per physical entity
+	// Alarm type n is represented by bit n%8 (least significant first) of octet n/8
+	active := make(map[string][]int)
+	var entIdxs []string
+	for ent, l := range r["alarmList"] {
+		for i, b := range []byte(l.OctetString) {
+			for pos := 0; pos < 8; pos++ {
+				if (b>>pos)&1 == 1 {
+					active[ent] = append(active[ent], i*8+pos)
+				}
+			}
+		}
+		if len(active[ent]) > 0 {
+			entIdxs = append(entIdxs, ent)
+		}
+	}
+
+	if len(entIdxs) == 0 {
+		return out, nil
+	}
+
+	names, err := sd.getmulti(oids["entPhysName"], entIdxs)
+	if err != nil {
+		return out, err
+	}
+
+	// Alarm description index of entity is found by entity vendor type (ceAlarmDescrMapTable)
+	types, err := sd.getmulti(oids["entPhysType"], entIdxs)
+	if err != nil {
+		return out, err
+	}
+
+	descrIdxs := make(map[string]string)
+	for i, v := range r["descrVendor"] {
+		descrIdxs[strings.TrimPrefix(v.ObjectIdentifier, ".")] = i
+	}
+
+	// Latest assert time of alarms from alarm history table
+	for _, n := range []string{"histType", "histEntIdx", "histAlType", "histTime"} {
+		res, err := sd.snmpSession.Walk(oids[n], true, true)
+		if err != nil && sd.handleErr(err) {
+			return out, err
+		}
+		r[n] = res
+	}
+
+	raised := make(map[string]uint64)
+	for i, t := range r["histType"] {
+		// 1 - asserted, 2 - cleared
+		if t.Integer != 1 {
+			continue
+		}
+
+		k := fmt.Sprintf("%d.%d", r["histEntIdx"][i].Integer, r["histAlType"][i].Integer)
+		if ts := r["histTime"][i].TimeTicks; ts > raised[k] {
+			raised[k] = ts
+		}
+	}
+
+	sys, err := sd.System([]string{"UpTime"})
+	if err != nil {
+		return out, err
+	}
+
+	for _, ent := range entIdxs {
+		src := "entity" + ent
+		if n, ok := names[oids["entPhysName"]+"."+ent]; ok && n.OctetString != "" {
+			src = n.OctetString
+		}
+
+		dIdx := descrIdxs[strings.TrimPrefix(types[oids["entPhysType"]+"."+ent].ObjectIdentifier, ".")]
+		for _, at := range active[ent] {
+			k := fmt.Sprintf("%s.%d", dIdx, at)
+
+			sev, ok := severity[r["descrSev"][k].Integer]
+			if !ok {
+				sev = "indeterminate"
+			}
+
+			text := strings.TrimSpace(r["descrText"][k].OctetString)
+			if text == "" {
+				text = fmt.Sprintf("alarm type %d", at)
+			}
+
+			a := &AlarmInfo{
+				Source:   ValString{Value: src, IsSet: true},
+				Severity: ValString{Value: sev, IsSet: true},
+				Text:     ValString{Value: text, IsSet: true},
+			}
+
+			if ts, ok := raised[fmt.Sprintf("%s.%d", ent, at)]; ok && sys.UpTime.IsSet {
+				a.setRaised(TicksTime(sys.UpTime.Value, ts))
+			}
+
+			out = append(out, a)
+		}
+	}
+
+	return out, nil
+}
+
+// Get info from .iso.org.dod.internet.private.enterprises.cisco.ciscoMgmt.ciscoVtpMIB.vtpMIBObjects.vlanInfo.vtpVlanTable
+// Returns ethernet vlan id-s and names
+func (sd *deviceCisco) D1qVlans() (map[string]string, error) {
+	out := make(map[string]string)
+
+	r, err := sd.walkCols(map[string]string{
+		"type": ".1.3.6.1.4.1.9.9.46.1.3.1.1.3",
+		"name": ".1.3.6.1.4.1.9.9.46.1.3.1.1.4",
+	})
+	if err != nil {
+		return out, err
+	}
+
+	for idx, t := range r["type"] {
+		// 1 - ethernet
+		if t.Integer != 1 {
+			continue
+		}
+
+		// Index is <managementDomainIndex>.<vtpVlanIndex>
+		p := strings.Split(idx, ".")
+		out[p[len(p)-1]] = r["name"][idx].OctetString
+	}
+
+	return out, nil
+}
+
+// Get info from CISCO-VTP-MIB vlanTrunkPortTable and CISCO-VLAN-MEMBERSHIP-MIB vmMembershipTable
+// Returns vlan port relations. Ports map keys are ifIndexes
+func (sd *deviceCisco) D1qVlanInfo() (map[string]*D1qVlanInfo, error) {
+	out := make(map[string]*D1qVlanInfo)
+
+	vlans, err := sd.D1qVlans()
+	if err != nil {
+		return out, err
+	}
+
+	for v, n := range vlans {
+		out[v] = &D1qVlanInfo{
+			Name:  n,
+			Ports: make(map[int]*D1qVlanBrPort),
+		}
+	}
+
+	// Adds port to vlan if vlan is present
+	addPort := func(vlan, ifIdx int, untag bool) {
+		if vi, ok := out[strconv.Itoa(vlan)]; ok {
+			vi.Ports[ifIdx] = &D1qVlanBrPort{IfIdx: ifIdx, UnTag: untag}
+		}
+	}
+
+	// trunk ports
+	t, err := sd.walkCols(map[string]string{
+		"enabled":   ".1.3.6.1.4.1.9.9.46.1.6.1.1.4",
+		"native":    ".1.3.6.1.4.1.9.9.46.1.6.1.1.5",
+		"status":    ".1.3.6.1.4.1.9.9.46.1.6.1.1.14",
+		"enabled2k": ".1.3.6.1.4.1.9.9.46.1.6.1.1.17",
+		"enabled3k": ".1.3.6.1.4.1.9.9.46.1.6.1.1.18",
+		"enabled4k": ".1.3.6.1.4.1.9.9.46.1.6.1.1.19",
+	})
+	if err != nil {
+		return out, err
+	}
+
+	// Enabled vlan bitmaps and their first vlan ids
+	bitmaps := map[string]int{"enabled": 0, "enabled2k": 1024, "enabled3k": 2048, "enabled4k": 3072}
+
+	trunks := make(map[string]bool)
+	for i, st := range t["status"] {
+		// 1 - trunking
+		idx, err := strconv.Atoi(i)
+		if err != nil || st.Integer != 1 {
+			continue
+		}
+		trunks[i] = true
+
+		for col, first := range bitmaps {
+			if b, ok := t[col][i]; ok {
+				for p := range BitMap([]byte(b.OctetString)) {
+					addPort(first+p-1, idx, false)
+				}
+			}
+		}
+
+		if n, ok := t["native"][i]; ok {
+			addPort(int(n.Integer), idx, true)
+		}
+	}
+
+	// access ports (vmVlan)
+	r, err := sd.snmpSession.Walk(".1.3.6.1.4.1.9.9.68.1.2.2.1.2", true, true)
+	if err != nil && sd.handleErr(err) {
+		return out, err
+	}
+
+	for i, v := range r {
+		if idx, err := strconv.Atoi(i); err == nil && !trunks[i] {
+			addPort(int(v.Integer), idx, true)
+		}
+	}
+
+	return out, nil
+}
+
+// Apply VLAN configuration lines in configuration transaction
+func (sd *deviceCisco) vlanCfg(lines []string) error {
+	if _, err := CfgTransaction(sd, lines, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Create VLAN
+func (sd *deviceCisco) AddVlan(vid int, name string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := vlanAddCheck(cur, vid); err != nil {
+		return err
+	}
+
+	lines := []string{"vlan " + strconv.Itoa(vid)}
+	if name != "" {
+		lines = append(lines, "name "+name)
+	}
+	lines = append(lines, "exit")
+
+	return sd.vlanCfg(lines)
+}
+
+// Set via CLI
+// Remove VLAN
+func (sd *deviceCisco) DelVlan(vid int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanDelCheck(cur, vid); err != nil {
+		return err
+	}
+
+	return sd.vlanCfg([]string{"no vlan " + strconv.Itoa(vid)})
+}
+
+// Set via CLI
+// Rename VLAN
+func (sd *deviceCisco) RenameVlan(vid int, name string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := vlanExists(cur, vid); err != nil {
+		return err
+	}
+
+	return sd.vlanCfg([]string{"vlan " + strconv.Itoa(vid), "name " + name, "exit"})
+}
+
+// Set via CLI
+// Set VLAN port membership. Access port is converted to trunk when tagged VLAN is added.
+// set - map of ifIndexes and their membership modes (tagged|untagged|none)
+func (sd *deviceCisco) SetVlanPorts(vid int, set map[string]string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPortsCheck(cur, vid, set, vlanAccessMoves(cur, set)); err != nil {
+		return err
+	}
+
+	idxs := make([]string, 0, len(set))
+	for k := range set {
+		idxs = append(idxs, k)
+	}
+	sort.Strings(idxs)
+
+	names, err := sd.ifDescrs(idxs)
+	if err != nil {
+		return err
+	}
+
+	v := strconv.Itoa(vid)
+	var lines []string
+	for _, i := range idxs {
+		n, _ := strconv.Atoi(i)
+		trunk := vlanTrunkPort(cur, n)
+		untag := vlanPortUntagged(cur, n)
+
+		lines = append(lines, "interface "+names[i])
+		switch set[i] {
+		case VlanPortTagged:
+			if !trunk {
+				lines = append(lines, "switchport mode trunk")
+				if untag > 0 {
+					u := strconv.Itoa(untag)
+					lines = append(lines, "switchport trunk native vlan "+u, "switchport trunk allowed vlan "+u+","+v)
+				} else {
+					lines = append(lines, "switchport trunk allowed vlan "+v)
+				}
+			} else {
+				lines = append(lines, "switchport trunk allowed vlan add "+v)
+			}
+		case VlanPortUntagged:
+			if trunk {
+				lines = append(lines, "switchport trunk native vlan "+v, "switchport trunk allowed vlan add "+v)
+			} else {
+				lines = append(lines, "switchport mode access", "switchport access vlan "+v)
+			}
+		case VlanPortNone:
+			if trunk {
+				lines = append(lines, "switchport trunk allowed vlan remove "+v)
+			} else if untag == vid {
+				lines = append(lines, "no switchport access vlan")
+			}
+		}
+		lines = append(lines, "exit")
+	}
+
+	return sd.vlanCfg(lines)
+}
+
+// Set via CLI
+// Set access VLAN of access ports and native VLAN of trunk ports
+// set - map of ifIndexes and their VLAN ids
+func (sd *deviceCisco) SetPvid(set map[string]int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPvidCheck(cur, set); err != nil {
+		return err
+	}
+
+	idxs := make([]string, 0, len(set))
+	for k := range set {
+		idxs = append(idxs, k)
+	}
+	sort.Strings(idxs)
+
+	names, err := sd.ifDescrs(idxs)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, i := range idxs {
+		n, _ := strconv.Atoi(i)
+		v := strconv.Itoa(set[i])
+
+		lines = append(lines, "interface "+names[i])
+		if vlanTrunkPort(cur, n) {
+			lines = append(lines, "switchport trunk native vlan "+v)
+		} else {
+			lines = append(lines, "switchport access vlan "+v)
+		}
+		lines = append(lines, "exit")
+	}
+
+	return sd.vlanCfg(lines)
+}
+
+// Running configuration checkpoint file for configuration transactions
+const ciscoCfgCheckpoint = "flash:godevman-checkpoint.cfg"
+
+// Open configuration transaction.
+// Saves running configuration checkpoint for configure replace based rollback.
+// Opens persistent cli session which will be closed by CfgCommit or CfgRollback.
+func (sd *deviceCisco) CfgOpen() error {
+	if err := sd.OpenCli(); err != nil {
+		return err
+	}
+
+	opts := &CliCmdOpts{
+		ChkErr: true,
+		// Accept proposed destination file name
+		Interact: []CliInteract{{Re: `Destination filename \[[^\]]*\]\?\s*$`}},
+	}
+
+	_, err := sd.RunCmds([]string{"copy running-config " + ciscoCfgCheckpoint}, opts)
+	if err != nil {
+		return sd.cfgClose(fmt.Errorf("cli command error: %v", err))
+	}
+	sd.cliSession.cfgTx = true
+
+	return nil
+}
+
+// Apply configuration lines in configuration mode.
+// Cisco has no candidate configuration, lines are applied to running configuration.
+// Running configuration will be replaced with checkpoint on error.
+func (sd *deviceCisco) CfgApply(lines []string) error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	cmds := append([]string{"configure terminal"}, lines...)
+	cmds = append(cmds, "end")
+
+	_, err := sd.RunCmds(cmds, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgAbort(fmt.Errorf("cli command error: %v", err), sd.CfgRollback)
+	}
+
+	return nil
+}
+
+// Get difference between checkpoint and running configuration
+func (sd *deviceCisco) CfgDiff() (string, error) {
+	if err := sd.cfgChkOpen(); err != nil {
+		return "", err
+	}
+
+	cmd := "show archive config differences " + ciscoCfgCheckpoint + " system:running-config"
+	res, err := sd.RunCmdsStructured([]string{cmd}, nil)
+	if err != nil {
+		return "", fmt.Errorf("cli command error: %v", err)
+	}
+
+	return strings.TrimSpace(res[0].Output), nil
+}
+
+// Save running configuration and close transaction
+func (sd *deviceCisco) CfgCommit() error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	_, err := sd.RunCmds([]string{"write memory"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgAbort(fmt.Errorf("cli command error: %v", err), sd.CfgRollback)
+	}
+
+	_, err = sd.RunCmds([]string{"delete /force " + ciscoCfgCheckpoint}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		err = fmt.Errorf("checkpoint delete error: %v", err)
+	}
+
+	return sd.cfgClose(err)
+}
+
+// Replace running configuration with checkpoint and close transaction
+func (sd *deviceCisco) CfgRollback() error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	// Leave configuration mode if apply was interrupted
+	_, _ = sd.RunCmds([]string{"end"}, nil)
+
+	cmds := []string{
+		"configure replace " + ciscoCfgCheckpoint + " force",
+		"delete /force " + ciscoCfgCheckpoint,
+	}
+
+	_, err := sd.RunCmds(cmds, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		err = fmt.Errorf("cli command error: %v", err)
+	}
+
+	return sd.cfgClose(err)
+}
+
+// Get running config
+func (sd *deviceCisco) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"show running-config"}, "exit")
+}
+
+// Get startup config
+func (sd *deviceCisco) StartupCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"show startup-config"}, "exit")
+}
+
+// Backup running config to scp server (copy running-config scp:)
+func (sd *deviceCisco) DoBackup() error {
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+	user, pass := sd.backupCred()
+	targetFile, t := sd.backupTarget(".cfg")
+
+	if err := sd.backupRecvDefPort("22"); err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	// Start embedded receiver if requested
+	rcv, err := sd.backupRecvStart("scp")
+	if err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	// Password is answered to prompt to keep it out of command history and output
+	url := "scp://" + user + "@" + host + "/" + strings.TrimPrefix(targetFile, "/")
+	opts := &CliCmdOpts{
+		ChkErr: true,
+		// Answer password prompt, accept proposed remote host, username and file name
+		Interact: []CliInteract{
+			{Re: `(?i)password:\s*$`, Send: pass},
+			{Re: `\[[^\]]*\]\?\s*$`},
+		},
+	}
+
+	res, err := sd.RunCmdsStructured(sd.cliExit([]string{"copy running-config " + url}, "exit"), opts)
+	if err == nil && !regexp.MustCompile(`\d+ bytes copied`).MatchString(res[0].Output) {
+		err = fmt.Errorf("no confirm for copy success, output: %s", res[0].Output)
+	}
+	if err != nil {
+		if rcv != nil {
+			rcv.stop()
+		}
+		return sd.backupDone(host, targetFile, t, fmt.Errorf("cli error: %v", err))
+	}
+
+	if rcv != nil {
+		err = sd.backupRecvFinish(rcv, targetFile)
+	}
+
+	return sd.backupDone(host, targetFile, t, err)
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceCisco) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
+}
+
+// Replace running config with config from scp server (configure replace) and verify it
+func (sd *deviceCisco) DoRestore(f *BackupFile) error {
+	if err := sd.restoreCheck(f); err != nil {
+		return err
+	}
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+	user, pass := sd.backupCred()
+
+	if err := sd.backupRecvDefPort("22"); err != nil {
+		return err
+	}
+
+	// Start embedded server if requested
+	srv, err := sd.backupServeStart("scp", f)
+	if err != nil {
+		return err
+	}
+
+	// Password is answered to prompt to keep it out of command history and output
+	url := "scp://" + user + "@" + host + "/" + strings.TrimPrefix(f.Name, "/")
+	opts := &CliCmdOpts{
+		ChkErr: true,
+		// Answer password prompt, accept proposed remote host, username and file name
+		Interact: []CliInteract{
+			{Re: `(?i)password:\s*$`, Send: pass},
+			{Re: `\[[^\]]*\]\?\s*$`},
+		},
+	}
+
+	res, err := sd.RunCmdsStructured(sd.cliExit([]string{"configure replace " + url + " force"}, "exit"), opts)
+	if srv != nil {
+		srv.stop()
+	}
+	if err == nil && !regexp.MustCompile(`(?i)rollback done`).MatchString(res[0].Output) {
+		err = fmt.Errorf("no confirm for configure replace success, output: %s", res[0].Output)
+	}
+	if err != nil {
+		return fmt.Errorf("cli error: %v", err)
+	}
+
+	cfg, err := sd.RuningCfg()
+	if err != nil {
+		return fmt.Errorf("restore verification failed: %v", err)
+	}
+
+	return sd.restoreVerify(cfg, f)
+}
@@ -299,3 +299,68 @@ func (sd *deviceComap) GeneratorInfo(targets []string) (GenInfo, error) {
 
 	return out, nil
 }
+
+// Get active alarms from generator controller alarm list
+// Alarm list items are strings like "*Wrn Batt volt" where "*" marks still active alarm
+// and prefix is alarm protection type.
+func (sd *deviceComap) Alarms() ([]*AlarmInfo, error) {
+	var out []*AlarmInfo
+
+	oid := sd.sysObjectId + ".2"
+
+	// Alarm list (16 items)
+	var rIdxs []string
+	for i := 10650; i < 10666; i++ {
+		rIdxs = append(rIdxs, fmt.Sprintf("%d.0", i))
+	}
+
+	severity := map[string]string{
+		"Sd":  "critical",
+		"Stp": "critical",
+		"BOC": "major",
+		"Slo": "major",
+		"Fls": "minor",
+		"Wrn": "warning",
+		"Hst": "warning",
+	}
+
+	r, err := sd.getmulti(oid, rIdxs)
+	if err != nil {
+		return out, err
+	}
+
+	for _, i := range rIdxs {
+		d, ok := r[oid+"."+i]
+		if !ok {
+			continue
+		}
+
+		item := strings.TrimSpace(d.OctetString)
+		if item == "" {
+			continue
+		}
+
+		// Skip alarms which are not active anymore
+		if !strings.HasPrefix(item, "*") {
+			continue
+		}
+		item = strings.TrimPrefix(item, "*")
+
+		a := &AlarmInfo{
+			Source:   ValString{Value: "generator", IsSet: true},
+			Severity: ValString{Value: "indeterminate", IsSet: true},
+			Text:     ValString{Value: item, IsSet: true},
+		}
+
+		if p := strings.SplitN(item, " ", 2); len(p) == 2 {
+			if s, ok := severity[p[0]]; ok {
+				a.Severity.Value = s
+				a.Text.Value = p[1]
+			}
+		}
+
+		out = append(out, a)
+	}
+
+	return out, nil
+}
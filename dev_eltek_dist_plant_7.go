@@ -1,6 +1,8 @@
 package godevman
 
-import "strings"
+import (
+	"strings"
+)
 
 // Adds Eltek Distributed Plant v7 PSU specific SNMP functionality to snmpCommon type
 type deviceEltekDP7 struct {
@@ -21,3 +23,88 @@ func (sd *deviceEltekDP7) SwVersion() (string, error) {
 
 	return out, nil
 }
+
+// Get info from .iso.org.dod.internet.private.enterprises.eltek.eltekDistributedPlantV7 tree
+// Valid targets values: "All", "Battery", "Rectifier", "Mains", "Load", "Alarm"
+func (sd *deviceEltekDP7) Sensors(targets []string) (map[string]map[string]map[string]SensorVal, error) {
+	out := make(map[string]map[string]map[string]SensorVal)
+
+	oid := ".1.3.6.1.4.1.12148.9"
+	idxs := map[string]string{
+		"batteryVoltage":    "3.2.0",
+		"batteryCurrent":    "3.3.0",
+		"batteryTemp":       "3.4.0",
+		"batteryCapacity":   "3.16.0",
+		"rectifiersNumber":  "5.1.0",
+		"rectifiersCurrent": "5.2.0",
+		"rectifiersLoad":    "5.3.0",
+		"mainsStatus":       "2.1.0",
+		"mainsVoltage":      "2.2.0",
+		"loadCurrent":       "4.1.0",
+		"alarmMajor":        "7.1.0",
+		"alarmMinor":        "7.2.0",
+		"alarmMains":        "7.3.0",
+		"alarmBatteryLow":   "7.4.0",
+		"alarmRectifier":    "7.5.0",
+	}
+
+	groups := map[string][]string{
+		"Battery":   {"batteryVoltage", "batteryCurrent", "batteryTemp", "batteryCapacity"},
+		"Rectifier": {"rectifiersNumber", "rectifiersCurrent", "rectifiersLoad"},
+		"Mains":     {"mainsStatus", "mainsVoltage"},
+		"Load":      {"loadCurrent"},
+		"Alarm":     {"alarmMajor", "alarmMinor", "alarmMains", "alarmBatteryLow", "alarmRectifier"},
+	}
+
+	t, err := sensorTargets(targets, "Battery", "Rectifier", "Mains", "Load", "Alarm")
+	if err != nil || len(t) == 0 {
+		return out, err
+	}
+
+	r, err := sd.getmulti(oid, sensorIdxs(t, groups, idxs))
+	if err != nil {
+		return out, err
+	}
+
+	for o, d := range r {
+		if d.Vtype != "Integer" {
+			continue
+		}
+
+		switch o {
+		case oid + "." + idxs["batteryVoltage"]:
+			setSensorVal(out, "Battery", "Status", "Voltage", signedSensorVal(d.Integer, "V", 100))
+		case oid + "." + idxs["batteryCurrent"]:
+			setSensorVal(out, "Battery", "Status", "Current", signedSensorVal(d.Integer, "A", 10))
+		case oid + "." + idxs["batteryTemp"]:
+			setSensorVal(out, "Battery", "Status", "Temp", signedSensorVal(d.Integer, "°C", 1))
+		case oid + "." + idxs["batteryCapacity"]:
+			setSensorVal(out, "Battery", "Status", "Capacity", signedSensorVal(d.Integer, "%", 1))
+		case oid + "." + idxs["rectifiersNumber"]:
+			setSensorVal(out, "Rectifier", "Status", "Count", signedSensorVal(d.Integer, "", 1))
+		case oid + "." + idxs["rectifiersCurrent"]:
+			setSensorVal(out, "Rectifier", "Status", "Current", signedSensorVal(d.Integer, "A", 10))
+		case oid + "." + idxs["rectifiersLoad"]:
+			setSensorVal(out, "Rectifier", "Status", "Load", signedSensorVal(d.Integer, "%", 1))
+		case oid + "." + idxs["mainsStatus"]:
+			// 1 - mains ok, 2 - mains failure
+			setSensorVal(out, "Mains", "Status", "Ok", SensorVal{Bool: d.Integer == 1, IsSet: true})
+		case oid + "." + idxs["mainsVoltage"]:
+			setSensorVal(out, "Mains", "Status", "Voltage", signedSensorVal(d.Integer, "V", 1))
+		case oid + "." + idxs["loadCurrent"]:
+			setSensorVal(out, "Load", "Status", "Current", signedSensorVal(d.Integer, "A", 10))
+		case oid + "." + idxs["alarmMajor"]:
+			setSensorVal(out, "Alarm", "Status", "Major", SensorVal{Bool: d.Integer == 1, IsSet: true})
+		case oid + "." + idxs["alarmMinor"]:
+			setSensorVal(out, "Alarm", "Status", "Minor", SensorVal{Bool: d.Integer == 1, IsSet: true})
+		case oid + "." + idxs["alarmMains"]:
+			setSensorVal(out, "Alarm", "Status", "Mains", SensorVal{Bool: d.Integer == 1, IsSet: true})
+		case oid + "." + idxs["alarmBatteryLow"]:
+			setSensorVal(out, "Alarm", "Status", "BatteryLow", SensorVal{Bool: d.Integer == 1, IsSet: true})
+		case oid + "." + idxs["alarmRectifier"]:
+			setSensorVal(out, "Alarm", "Status", "Rectifier", SensorVal{Bool: d.Integer == 1, IsSet: true})
+		}
+	}
+
+	return out, nil
+}
@@ -1,6 +1,9 @@
 package godevman
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // Adds Eltek eNexus PSU specific SNMP functionality to snmpCommon type
 type deviceEltekEnexus struct {
@@ -59,3 +62,189 @@ func (sd *deviceEltekEnexus) SwVersion() (string, error) {
 	r, err := sd.getone(oid)
 	return strings.TrimSpace(r[oid].OctetString), err
 }
+
+// Eltek SP2-MIB status values
+var eltekSp2Status = map[int64]string{
+	0:  "error",
+	1:  "normal",
+	2:  "minorAlarm",
+	3:  "majorAlarm",
+	4:  "disabled",
+	5:  "disconnected",
+	6:  "notPresent",
+	7:  "minorAndMajor",
+	8:  "majorLow",
+	9:  "minorLow",
+	10: "majorHigh",
+	11: "minorHigh",
+	12: "event",
+	13: "valueVolt",
+	14: "valueAmp",
+	15: "valueTemp",
+	16: "valueUnit",
+	17: "valuePerCent",
+	18: "critical",
+	19: "warning",
+}
+
+// Get info from .iso.org.dod.internet.private.enterprises.eltek.eNexus.powerSystem tree
+// Valid targets values: "All", "Battery", "Rectifier", "Mains", "Load", "Alarm"
+func (sd *deviceEltekEnexus) Sensors(targets []string) (map[string]map[string]map[string]SensorVal, error) {
+	out := make(map[string]map[string]map[string]SensorVal)
+
+	oid := ".1.3.6.1.4.1.12148.10"
+	idxs := map[string]string{
+		"powerSystemStatus": "2.1.0",
+		"mainsStatus":       "3.1.0",
+		"mainsVoltage":      "3.4.1.6.1",
+		"rectifiersStatus":  "5.1.0",
+		"rectifiersCurrent": "5.2.5.0",
+		"rectifiersUsed":    "5.3.5.0",
+		"rectifiersNumber":  "5.4.0",
+		"rectifiersActive":  "5.5.0",
+		"loadStatus":        "9.1.0",
+		"loadCurrent":       "9.2.5.0",
+		"batteryStatus":     "10.1.0",
+		"batteryVoltage":    "10.5.5.0",
+		"batteryCurrent":    "10.6.5.0",
+		"batteryTemp":       "10.7.5.0",
+		"batteryTimeLeft":   "10.8.5.0",
+		"batteryCapacity":   "10.9.5.0",
+	}
+
+	groups := map[string][]string{
+		"Battery": {
+			"batteryStatus", "batteryVoltage", "batteryCurrent", "batteryTemp", "batteryTimeLeft",
+			"batteryCapacity",
+		},
+		"Rectifier": {"rectifiersStatus", "rectifiersCurrent", "rectifiersUsed", "rectifiersNumber", "rectifiersActive"},
+		"Mains":     {"mainsStatus", "mainsVoltage"},
+		"Load":      {"loadStatus", "loadCurrent"},
+		"Alarm":     {"powerSystemStatus", "mainsStatus", "rectifiersStatus", "loadStatus", "batteryStatus"},
+	}
+
+	t, err := sensorTargets(targets, "Battery", "Rectifier", "Mains", "Load", "Alarm")
+	if err != nil || len(t) == 0 {
+		return out, err
+	}
+
+	r, err := sd.getmulti(oid, sensorIdxs(t, groups, idxs))
+	if err != nil {
+		return out, err
+	}
+
+	statusVal := func(v int64) SensorVal {
+		s, ok := eltekSp2Status[v]
+		if !ok {
+			s = fmt.Sprintf("unkn(%d)", v)
+		}
+		return SensorVal{String: s, Bool: v != 1, IsSet: true}
+	}
+
+	for o, d := range r {
+		if d.Vtype != "Integer" {
+			continue
+		}
+
+		switch o {
+		case oid + "." + idxs["powerSystemStatus"]:
+			setSensorVal(out, "Alarm", "Status", "PowerSystem", statusVal(d.Integer))
+		case oid + "." + idxs["mainsStatus"]:
+			setSensorVal(out, "Mains", "Status", "Ok", SensorVal{Bool: d.Integer == 1, IsSet: true})
+			setSensorVal(out, "Alarm", "Status", "Mains", statusVal(d.Integer))
+		case oid + "." + idxs["mainsVoltage"]:
+			setSensorVal(out, "Mains", "Status", "Voltage", signedSensorVal(d.Integer, "V", 1))
+		case oid + "." + idxs["rectifiersStatus"]:
+			setSensorVal(out, "Alarm", "Status", "Rectifier", statusVal(d.Integer))
+		case oid + "." + idxs["rectifiersCurrent"]:
+			setSensorVal(out, "Rectifier", "Status", "Current", signedSensorVal(d.Integer, "A", 10))
+		case oid + "." + idxs["rectifiersUsed"]:
+			setSensorVal(out, "Rectifier", "Status", "Load", signedSensorVal(d.Integer, "%", 1))
+		case oid + "." + idxs["rectifiersNumber"]:
+			setSensorVal(out, "Rectifier", "Status", "Count", signedSensorVal(d.Integer, "", 1))
+		case oid + "." + idxs["rectifiersActive"]:
+			setSensorVal(out, "Rectifier", "Status", "Active", signedSensorVal(d.Integer, "", 1))
+		case oid + "." + idxs["loadStatus"]:
+			setSensorVal(out, "Alarm", "Status", "Load", statusVal(d.Integer))
+		case oid + "." + idxs["loadCurrent"]:
+			setSensorVal(out, "Load", "Status", "Current", signedSensorVal(d.Integer, "A", 10))
+		case oid + "." + idxs["batteryStatus"]:
+			setSensorVal(out, "Alarm", "Status", "Battery", statusVal(d.Integer))
+		case oid + "." + idxs["batteryVoltage"]:
+			setSensorVal(out, "Battery", "Status", "Voltage", signedSensorVal(d.Integer, "V", 100))
+		case oid + "." + idxs["batteryCurrent"]:
+			setSensorVal(out, "Battery", "Status", "Current", signedSensorVal(d.Integer, "A", 10))
+		case oid + "." + idxs["batteryTemp"]:
+			setSensorVal(out, "Battery", "Status", "Temp", signedSensorVal(d.Integer, "°C", 1))
+		case oid + "." + idxs["batteryTimeLeft"]:
+			setSensorVal(out, "Battery", "Status", "TimeLeft", signedSensorVal(d.Integer, "min", 1))
+		case oid + "." + idxs["batteryCapacity"]:
+			setSensorVal(out, "Battery", "Status", "Capacity", signedSensorVal(d.Integer, "%", 1))
+		}
+	}
+
+	return out, nil
+}
+
+// Get active alarms from .iso.org.dod.internet.private.enterprises.eltek.eNexus.powerSystem tree status values
+func (sd *deviceEltekEnexus) Alarms() ([]*AlarmInfo, error) {
+	var out []*AlarmInfo
+
+	oid := ".1.3.6.1.4.1.12148.10"
+	idxs := map[string]string{
+		"2.1.0":  "PowerSystem",
+		"3.1.0":  "Mains",
+		"5.1.0":  "Rectifier",
+		"9.1.0":  "Load",
+		"10.1.0": "Battery",
+	}
+
+	severity := map[string]string{
+		"error":         "critical",
+		"critical":      "critical",
+		"majorAlarm":    "major",
+		"minorAndMajor": "major",
+		"majorLow":      "major",
+		"majorHigh":     "major",
+		"minorAlarm":    "minor",
+		"minorLow":      "minor",
+		"minorHigh":     "minor",
+		"warning":       "warning",
+		"disconnected":  "warning",
+	}
+
+	var rIdxs []string
+	for i := range idxs {
+		rIdxs = append(rIdxs, i)
+	}
+
+	r, err := sd.getmulti(oid, rIdxs)
+	if err != nil {
+		return out, err
+	}
+
+	for i, n := range idxs {
+		d, ok := r[oid+"."+i]
+		if !ok || d.Vtype != "Integer" {
+			continue
+		}
+
+		s, ok := eltekSp2Status[d.Integer]
+		if !ok {
+			s = fmt.Sprintf("unkn(%d)", d.Integer)
+		}
+
+		sev, ok := severity[s]
+		if !ok {
+			continue
+		}
+
+		out = append(out, &AlarmInfo{
+			Source:   ValString{Value: n, IsSet: true},
+			Severity: ValString{Value: sev, IsSet: true},
+			Text:     ValString{Value: n + " " + s, IsSet: true},
+		})
+	}
+
+	return out, nil
+}
@@ -226,6 +226,8 @@ func (sd *deviceEricssonMlPt) RlInfo() (map[string]*RlRadioIfInfo, error) {
 // Make http Get request and return byte slice of body.
 // Argument string should contain request parameters.
 func (sd *deviceEricssonMlPt) WebApiGet(params string) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
 	client := sd.webSession.client
 	if sd.webSession.client == nil {
 		// setup client
@@ -256,6 +258,11 @@ func (sd *deviceEricssonMlPt) WebApiGet(params string) ([]byte, error) {
 // Login via web API and stores web session in deviceEricssonMlPt.webSession.client.
 // Use this before use of methods which are accessing restricted device web API.
 func (sd *deviceEricssonMlPt) WebAuth(userPass []string) error {
+	if reuse, err := sd.webSession.lease(); err != nil || reuse {
+		return err
+	}
+	defer sd.webSession.leased()
+
 	// setup client
 	client, err := sd.webClient(nil)
 	if err != nil {
@@ -320,6 +327,11 @@ func (sd *deviceEricssonMlPt) WebAuth(userPass []string) error {
 // Logout via web API and delete web session from deviceEricssonMlPt.webSession.client.
 // Use this after use of methods which are accessing restricted device web API.
 func (sd *deviceEricssonMlPt) WebLogout() error {
+	if sd.webSession.unlease() {
+		return nil
+	}
+	defer sd.webSession.unleased()
+
 	if sd.webSession.client == nil {
 		return nil
 	}
@@ -468,8 +480,24 @@ func (sd *deviceEricssonMlPt) SwVersion() (string, error) {
 	return sw, err
 }
 
-// Get last backup info
-func (sd *deviceEricssonMlPt) LastBackup() (*BackupInfo, error) {
+// CDB backup and restore status provided by MINI-LINK PT web API
+type mlPtCdbStatus struct {
+	I6LastRestoreServerIPV6 string `json:"i6LastRestoreServerIPV6"`
+	BLastBackUpFile         string `json:"bLastBackUpFile"`
+	I6LastBackUpServerIPV6  string `json:"i6LastBackUpServerIPV6"`
+	TLastBackUpTime         int    `json:"tLastBackUpTime"`
+	ILastBackUpServer       int    `json:"iLastBackUpServer"`
+	TLastRestoreTime        int    `json:"tLastRestoreTime"`
+	TLastChangeTime         int    `json:"tLastChangeTime"`
+	EStatus                 int    `json:"eStatus"`
+	TStatusTimestamp        int    `json:"tStatusTimestamp"`
+	BProgress               int    `json:"bProgress"`
+	EAutomaticRollback      int    `json:"eAutomaticRollback"`
+	TPendingRollback        int    `json:"tPendingRollback"`
+}
+
+// Get CDB backup and restore status
+func (sd *deviceEricssonMlPt) cdbStatus() (*mlPtCdbStatus, error) {
 	if err := sd.WebAuth(sd.webSession.cred); err != nil {
 		return nil, fmt.Errorf("error: WebAuth - %s", err)
 	}
@@ -484,35 +512,29 @@ func (sd *deviceEricssonMlPt) LastBackup() (*BackupInfo, error) {
 		return nil, fmt.Errorf("errors: WebLogout - %s", err)
 	}
 
-	// Last backup info provided by MINI-LINK PT web API
-	type lastBackup struct {
-		Cdb struct {
-			I6LastRestoreServerIPV6 string `json:"i6LastRestoreServerIPV6"`
-			BLastBackUpFile         string `json:"bLastBackUpFile"`
-			I6LastBackUpServerIPV6  string `json:"i6LastBackUpServerIPV6"`
-			TLastBackUpTime         int    `json:"tLastBackUpTime"`
-			ILastBackUpServer       int    `json:"iLastBackUpServer"`
-			TLastRestoreTime        int    `json:"tLastRestoreTime"`
-			TLastChangeTime         int    `json:"tLastChangeTime"`
-			EStatus                 int    `json:"eStatus"`
-			TStatusTimestamp        int    `json:"tStatusTimestamp"`
-			BProgress               int    `json:"bProgress"`
-			EAutomaticRollback      int    `json:"eAutomaticRollback"`
-			TPendingRollback        int    `json:"tPendingRollback"`
-		} `json:"CDB"`
-	}
-
-	info := &lastBackup{}
+	info := &struct {
+		Cdb *mlPtCdbStatus `json:"CDB"`
+	}{}
 	err = json.Unmarshal(body, info)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal backup info failed: %s", err)
 	}
 
-	if info == nil {
+	if info.Cdb == nil {
 		return nil, fmt.Errorf("no backup info")
 	}
 
-	ip := ipconv.IntToIPv4(uint32(info.Cdb.ILastBackUpServer))
+	return info.Cdb, nil
+}
+
+// Get last backup info
+func (sd *deviceEricssonMlPt) LastBackup() (*BackupInfo, error) {
+	cdb, err := sd.cdbStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := ipconv.IntToIPv4(uint32(cdb.ILastBackUpServer))
 
 	// Reverse ip slice
 	for i, j := 0, len(ip)-1; i < j; i, j = i+1, j-1 {
@@ -520,17 +542,17 @@ func (sd *deviceEricssonMlPt) LastBackup() (*BackupInfo, error) {
 	}
 
 	out := new(BackupInfo)
-	if info.Cdb.TLastBackUpTime > 0 {
+	if cdb.TLastBackUpTime > 0 {
 		out.TargetIP = ip.String()
-		out.TargetFile = info.Cdb.BLastBackUpFile
-		out.Timestamp = info.Cdb.TLastBackUpTime
-		out.Progress = info.Cdb.BProgress
-		if info.Cdb.EStatus == 7 {
+		out.TargetFile = cdb.BLastBackUpFile
+		out.Timestamp = cdb.TLastBackUpTime
+		out.Progress = cdb.BProgress
+		if cdb.EStatus == 7 {
 			out.Success = true
 		}
 	}
 
-	return out, err
+	return out, nil
 }
 
 // Get RL neighbour info (map keys are local ifdescriptions or "0" for PtP links)
@@ -893,8 +915,29 @@ func (d *deviceEricssonMlPt) startCli(p *CliParams) error {
 	return nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceEricssonMlPt) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceEricssonMlPt) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceEricssonMlPt) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -904,12 +947,17 @@ func (sd *deviceEricssonMlPt) RunCmds(c []string, o *CliCmdOpts) ([]string, erro
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -952,16 +1000,34 @@ func (sd *deviceEricssonMlPt) DoBackup() error {
 
 	targetFile := sd.backupParams.BasePath + "/" + sd.backupParams.DevIdent + "_" + t.Format(time_iso8601_sec) + ".zip"
 
+	port, err := sd.backupRecvPort("22")
+	if err != nil {
+		return err
+	}
+
+	// Start embedded receiver if requested
+	rcv, err := sd.backupRecvStart("sftp")
+	if err != nil {
+		return err
+	}
+
 	cmds := []string{
-		"config common cdb backup filename " + targetFile + " ip " + host + " mode sftp password " + pass + " port 22 user " + user + ";_",
+		"config common cdb backup filename " + targetFile + " ip " + host + " mode sftp password " + pass + " port " + port + " user " + user + ";_",
 		"quit",
 	}
 
 	res, err := sd.RunCmds(cmds, &CliCmdOpts{ChkErr: true})
 	if err != nil {
+		if rcv != nil {
+			rcv.stop()
+		}
 		return fmt.Errorf("cli error: %v, output: %s", err, res)
 	}
 
+	if rcv != nil {
+		return sd.backupRecvFinish(rcv, targetFile)
+	}
+
 	for i := 0; i < 10; i++ {
 		time.Sleep(3 * time.Second)
 		b, err := sd.LastBackup()
@@ -976,3 +1042,162 @@ func (sd *deviceEricssonMlPt) DoBackup() error {
 
 	return fmt.Errorf("no confirm for backup success from web api")
 }
+
+// Command which confirms restored CDB and cancels pending automatic rollback
+const mlPtCdbConfirm = "config common cdb rollback confirm;_"
+
+// Restore CDB from sftp server. Restore progress is polled via web API.
+// Pending automatic rollback will be canceled if device is reachable after restore.
+func (sd *deviceEricssonMlPt) DoRestore(f *BackupFile) error {
+	if err := sd.restoreCheck(f); err != nil {
+		return err
+	}
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+	user, pass := sd.backupCred()
+
+	port, err := sd.backupRecvPort("22")
+	if err != nil {
+		return err
+	}
+
+	// Start embedded server if requested
+	srv, err := sd.backupServeStart("sftp", f)
+	if err != nil {
+		return err
+	}
+
+	t := time.Now()
+	cmds := []string{
+		"config common cdb restore filename " + f.Name + " ip " + host + " mode sftp password " + pass + " port " + port + " user " + user + ";_",
+	}
+
+	res, err := sd.RunCmds(sd.cliExit(cmds, "quit"), &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		if srv != nil {
+			srv.stop()
+		}
+		return fmt.Errorf("cli error: %v, output: %s", err, res)
+	}
+
+	// Device may restart during restore
+	var cdb *mlPtCdbStatus
+	for i := 0; i < 60; i++ {
+		time.Sleep(5 * time.Second)
+		c, err := sd.cdbStatus()
+		if err != nil {
+			continue
+		}
+
+		if t.Unix() <= int64(c.TLastRestoreTime) && c.BProgress == 100 {
+			cdb = c
+			break
+		}
+	}
+	if srv != nil {
+		srv.stop()
+	}
+
+	if cdb == nil {
+		return fmt.Errorf("no confirm for restore success from web api")
+	}
+	if cdb.EStatus != 7 {
+		return fmt.Errorf("restore failed, status: %d", cdb.EStatus)
+	}
+
+	if cdb.EAutomaticRollback == 0 || cdb.TPendingRollback == 0 {
+		return nil
+	}
+
+	// Device is reachable, keep restored configuration
+	res, err = sd.RunCmds(sd.cliExit([]string{mlPtCdbConfirm}, "quit"), &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return fmt.Errorf("cancel pending rollback failed: %v, output: %s", err, res)
+	}
+
+	cdb, err = sd.cdbStatus()
+	if err != nil {
+		return fmt.Errorf("web api error: %v", err)
+	}
+	if cdb.TPendingRollback != 0 {
+		return fmt.Errorf("automatic rollback is still pending")
+	}
+
+	return nil
+}
+
+// Get active alarms
+func (sd *deviceEricssonMlPt) Alarms() ([]*AlarmInfo, error) {
+	if err := sd.WebAuth(sd.webSession.cred); err != nil {
+		return nil, fmt.Errorf("error: WebAuth - %s", err)
+	}
+
+	body, err := sd.WebApiGet("CATEGORY=JSONREQUEST&CURRENT_ALARMS")
+	if err != nil {
+		return nil, fmt.Errorf("get request from device api failed: %s", err)
+	}
+
+	err = sd.WebLogout()
+	if err != nil {
+		return nil, fmt.Errorf("errors: WebLogout - %s", err)
+	}
+
+	// Active alarms info provided by MINI-LINK PT web API
+	type alarmsInfo struct {
+		CurrentAlarms []struct {
+			CurrentAlarmsEntry struct {
+				BSource          string `json:"bSource"`
+				BSpecificProblem string `json:"bSpecificProblem"`
+				BAdditional      string `json:"bAdditionalText"`
+				TRaisedTime      int    `json:"tRaisedTime"`
+				ESeverity        int    `json:"eSeverity"`
+				EAckState        int    `json:"eAckState"`
+			} `json:"CURRENT_ALARMS_ENTRY"`
+		} `json:"CURRENT_ALARMS"`
+	}
+
+	info := &alarmsInfo{}
+	err = json.Unmarshal(body, info)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal alarms info failed: %s", err)
+	}
+
+	var out []*AlarmInfo
+	for _, i := range info.CurrentAlarms {
+		e := i.CurrentAlarmsEntry
+
+		// eSeverity values follows ITU perceived severity
+		sev, ok := ituPerceivedSeverity[int64(e.ESeverity)]
+		if !ok || e.ESeverity == 1 {
+			sev = "indeterminate"
+		}
+
+		text := e.BSpecificProblem
+		if e.BAdditional != "" {
+			text += " - " + e.BAdditional
+		}
+
+		a := &AlarmInfo{
+			Source:   ValString{Value: e.BSource, IsSet: true},
+			Severity: ValString{Value: sev, IsSet: true},
+			Text:     ValString{Value: text, IsSet: true},
+			// eAckState: 1 - acknowledged, 2 - unacknowledged
+			Acked: ValBool{Value: e.EAckState == 1, IsSet: true},
+		}
+
+		if e.TRaisedTime > 0 {
+			a.setRaised(time.Unix(int64(e.TRaisedTime), 0))
+		}
+
+		out = append(out, a)
+	}
+
+	return out, nil
+}
+
+// Get running config
+func (sd *deviceEricssonMlPt) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"show running-config;_"}, "exit")
+}
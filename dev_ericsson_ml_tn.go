@@ -3,6 +3,7 @@ package godevman
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -39,6 +40,143 @@ func (sd *deviceEricssonMlTn) SwVersion() (string, error) {
 	return strings.TrimSpace(r[oid].OctetString), err
 }
 
+// Get info from XF-RADIOLINK-PTP-RADIO-MIB and XF-RADIOLINK-PTP-MODEM-MIB (tables are indexed by radio ifIndex)
+// Returns radio link info. Map keys are radio ifdescriptions
+func (sd *deviceEricssonMlTn) RlInfo() (map[string]*RlRadioIfInfo, error) {
+	out := make(map[string]*RlRadioIfInfo)
+
+	rfTable := ".1.3.6.1.4.1.193.81.3.4.3.1.3.1."    // xfRFBaseTable
+	modemTable := ".1.3.6.1.4.1.193.81.3.4.3.1.2.1." // xfModemTable
+	pm15Table := ".1.3.6.1.4.1.193.81.3.4.3.1.4.1."  // xfRLPm15MinTable (<ifIndex>.<interval>)
+	pm24Table := ".1.3.6.1.4.1.193.81.3.4.3.1.5.1."  // xfRLPm24HourTable (<ifIndex>.<interval>)
+
+	r, err := sd.walkCols(map[string]string{
+		"powerOut":   rfTable + "1",
+		"powerIn":    rfTable + "10",
+		"capacity":   modemTable + "6",
+		"modulation": modemTable + "11",
+		"snr":        modemTable + "14",
+	})
+	if err != nil {
+		return out, err
+	}
+
+	pmCols := func(t string) map[string]string {
+		return map[string]string{"es": t + "2", "ses": t + "3", "bbe": t + "4", "uas": t + "5"}
+	}
+	pm15, err := sd.walkCols(pmCols(pm15Table))
+	if err != nil {
+		return out, err
+	}
+	pm24, err := sd.walkCols(pmCols(pm24Table))
+	if err != nil {
+		return out, err
+	}
+	g15, g24 := g826Intervals(pm15), g826Intervals(pm24)
+
+	idxs := make([]string, 0, len(r["powerOut"]))
+	for idx := range r["powerOut"] {
+		idxs = append(idxs, idx)
+	}
+	if len(idxs) == 0 {
+		return out, nil
+	}
+
+	ifs, err := sd.IfInfo([]string{"Descr", "Name", "Admin", "Oper"}, idxs...)
+	if err != nil {
+		return out, err
+	}
+
+	for _, idx := range idxs {
+		i, rf := rlRadio(idx, ifs[idx])
+
+		if v, ok := r["powerOut"][idx]; ok {
+			rf.PowerOut = ValF64{Value: float64(v.Integer), IsSet: true}
+		}
+		// Input power and SNR are in 0.1 dBm (dB) units
+		if v, ok := r["powerIn"][idx]; ok {
+			rf.PowerIn = ValF64{Value: float64(v.Integer) / 10, IsSet: true}
+		}
+		if v, ok := r["snr"][idx]; ok {
+			rf.Snr = ValF64{Value: float64(v.Integer) / 10, IsSet: true}
+		}
+		if v, ok := r["modulation"][idx]; ok {
+			rf.Modulation = ValString{Value: strings.TrimSpace(v.OctetString), IsSet: true}
+		}
+		// Current capacity (kbit/s)
+		if v, ok := r["capacity"][idx]; ok {
+			if c, ok := snmpRawUint(v.Raw); ok {
+				rf.TxCapacity = ValInt{Value: int(c) * 1000, IsSet: true}
+			}
+		}
+
+		i.Perf15m, i.Perf24h = g15[idx], g24[idx]
+		if len(i.Perf15m) > 0 && i.Perf15m[0].Interval.Value == 0 {
+			c := i.Perf15m[0]
+			i.Es, i.Ses, i.Bbe, i.Uas = c.Es, c.Ses, c.Bbe, c.Uas
+		}
+
+		out[i.Descr.Value] = i
+	}
+
+	return out, nil
+}
+
+// Get info from XF-RADIOLINK-RLT-MIB far end table
+// Returns far end radio info. Map keys are local radio ifdescriptions
+func (sd *deviceEricssonMlTn) RlNbrInfo() (map[string]*RlRadioFeIfInfo, error) {
+	out := make(map[string]*RlRadioFeIfInfo)
+
+	feTable := ".1.3.6.1.4.1.193.81.3.4.1.1.14.1." // xfRLTFarEndTable
+
+	r, err := sd.walkCols(map[string]string{
+		"ip":    feTable + "5",
+		"name":  feTable + "6",
+		"ifIdx": feTable + "7",
+	})
+	if err != nil {
+		return out, err
+	}
+
+	idxs := make([]string, 0, len(r["ip"]))
+	for idx := range r["ip"] {
+		idxs = append(idxs, idx)
+	}
+	if len(idxs) == 0 {
+		return out, nil
+	}
+
+	ifs, err := sd.IfInfo([]string{"Descr"}, idxs...)
+	if err != nil {
+		return out, err
+	}
+
+	for _, idx := range idxs {
+		fe := new(RlRadioFeIfInfo)
+
+		if n, err := strconv.Atoi(idx); err == nil {
+			fe.IfIdx = ValInt{Value: n, IsSet: true}
+		}
+		if v := r["ip"][idx]; v.IPAddress != "" && v.IPAddress != "0.0.0.0" {
+			fe.Ip = ValString{Value: v.IPAddress, IsSet: true}
+		}
+		if v, ok := r["name"][idx]; ok {
+			fe.SysName = ValString{Value: strings.TrimSpace(v.OctetString), IsSet: true}
+		}
+		if v, ok := r["ifIdx"][idx]; ok {
+			fe.FeIfIdx = ValInt{Value: int(v.Integer), IsSet: true}
+		}
+
+		key := idx
+		if i, ok := ifs[idx]; ok && i.Descr.IsSet {
+			key = i.Descr.Value
+		}
+		out[key] = fe
+	}
+
+	return out, nil
+}
+
 // Prepare CLI session parameters
 func (sd *deviceEricssonMlTn) cliPrepare() (*CliParams, error) {
 	defParams, err := sd.snmpCommon.cliPrepare()
@@ -228,8 +366,29 @@ func (d *deviceEricssonMlTn) startCli(p *CliParams) error {
 	return nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceEricssonMlTn) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceEricssonMlTn) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceEricssonMlTn) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -239,12 +398,17 @@ func (sd *deviceEricssonMlTn) RunCmds(c []string, o *CliCmdOpts) ([]string, erro
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -260,3 +424,13 @@ func (sd *deviceEricssonMlTn) RunCmds(c []string, o *CliCmdOpts) ([]string, erro
 
 	return out, nil
 }
+
+// Get active alarms
+func (sd *deviceEricssonMlTn) Alarms() ([]*AlarmInfo, error) {
+	return sd.alarmMibAlarms()
+}
+
+// Get running config
+func (sd *deviceEricssonMlTn) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"show running-config"}, "exit")
+}
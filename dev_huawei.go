@@ -0,0 +1,131 @@
+package godevman
+
+import "strings"
+
+// Adds Huawei specific SNMP functionality to snmpCommon type
+type deviceHuawei struct {
+	snmpCommon
+}
+
+// Huawei GPON ONU last down cause values (HUAWEI-XPON-MIB hwGponDeviceOntControlLastDownCause)
+var huaweiOnuDownCause = map[int64]string{
+	1:  "LOS",
+	2:  "LOSi/LOBi",
+	3:  "LOFI",
+	4:  "SFI",
+	5:  "LOAI",
+	6:  "LOAMI",
+	7:  "deactive failure",
+	8:  "deactive success",
+	9:  "reset",
+	10: "re-register",
+	11: "popup fail",
+	13: "dying-gasp",
+	15: "LOKI",
+	18: "deactived due to ring",
+	30: "shut down ONT optical module",
+	31: "reset ONT by ONT command",
+	32: "reset ONT by ONT reset button",
+	33: "reset ONT by ONT software",
+	34: "deactived due to broadcast attack",
+	35: "operator check fail",
+	37: "rogue ONT detected by itself",
+}
+
+// Get info from HUAWEI-XPON-MIB
+// Returns OLT's GPON ONU info. Map keys are ONU serials
+func (sd *deviceHuawei) OnuInfo() (map[string]*OnuInfo, error) {
+	out := make(map[string]*OnuInfo)
+
+	base := ".1.3.6.1.4.1.2011.6.128.1.1.2."
+	oids := map[string]string{
+		"serial":    base + "43.1.3",
+		"descr":     base + "43.1.9",
+		"status":    base + "46.1.15",
+		"distance":  base + "46.1.20",
+		"downCause": base + "46.1.24",
+		"temp":      base + "51.1.1",
+		"bias":      base + "51.1.2",
+		"txPower":   base + "51.1.3",
+		"rxPower":   base + "51.1.4",
+		"oltRx":     base + "51.1.6",
+	}
+
+	r, err := sd.walkCols(oids)
+	if err != nil {
+		return out, err
+	}
+
+	ifs, err := sd.IfInfo([]string{"Descr"})
+	if err != nil {
+		return out, err
+	}
+
+	// Invalid optical value
+	const invalid = 2147483647
+
+	for idx, s := range r["serial"] {
+		// index is <PON port ifIndex>.<ONT id>
+		parts := strings.SplitN(idx, ".", 2)
+		if len(parts) != 2 || s.OctetString == "" {
+			continue
+		}
+
+		o := new(OnuInfo)
+
+		o.OltPort.Value = parts[0]
+		if i, ok := ifs[parts[0]]; ok && i.Descr.IsSet {
+			o.OltPort.Value = i.Descr.Value
+		}
+		o.OltPort.IsSet = true
+
+		if v, ok := r["descr"][idx]; ok {
+			o.Name.Value = strings.TrimSpace(v.OctetString)
+			o.Name.IsSet = true
+		}
+		if v, ok := r["status"][idx]; ok {
+			o.Online.Value = v.Integer == 1
+			o.Online.IsSet = true
+			o.State.Value = "offline"
+			if o.Online.Value {
+				o.State.Value = "online"
+			}
+			o.State.IsSet = true
+		}
+		if v, ok := r["downCause"][idx]; ok && v.Integer > 0 {
+			o.DownCause.Value = huaweiOnuDownCause[v.Integer]
+			if o.DownCause.Value == "" {
+				o.DownCause.Value = "unknown"
+			}
+			o.DownCause.IsSet = true
+		}
+		if v, ok := r["distance"][idx]; ok && v.Integer >= 0 {
+			o.Distance = SensorVal{
+				Unit:    "m",
+				Divisor: 1,
+				Value:   uint64(v.Integer),
+				IsSet:   true,
+			}
+		}
+		if v, ok := r["temp"][idx]; ok && v.Integer != invalid {
+			o.CpuTemp = signedSensorVal(v.Integer, "°C", 1)
+		}
+		if v, ok := r["bias"][idx]; ok && v.Integer != invalid {
+			o.LaserBias = signedSensorVal(v.Integer, "mA", 1)
+		}
+		if v, ok := r["txPower"][idx]; ok && v.Integer != invalid {
+			o.TxPower = signedSensorVal(v.Integer, "dBm", 100)
+		}
+		if v, ok := r["rxPower"][idx]; ok && v.Integer != invalid {
+			o.RxPower = signedSensorVal(v.Integer, "dBm", 100)
+		}
+		// OLT RX power is reported with 100 dBm offset
+		if v, ok := r["oltRx"][idx]; ok && v.Integer != invalid {
+			o.OltRxPower = signedSensorVal(v.Integer-10000, "dBm", 100)
+		}
+
+		out[GponSerial(s.OctetString)] = o
+	}
+
+	return out, nil
+}
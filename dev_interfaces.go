@@ -46,6 +46,21 @@ type DevVlanReader interface {
 	D1qVlanInfo() (map[string]*D1qVlanInfo, error)
 }
 
+// Dot1Q VLAN provisioning. VLAN ids are 1-4094
+type DevVlanWriter interface {
+	// Create VLAN. Args: vlan id, name
+	AddVlan(int, string) error
+	// Remove VLAN
+	DelVlan(int) error
+	// Rename VLAN. Args: vlan id, name
+	RenameVlan(int, string) error
+	// Set VLAN port membership. Map keys are ifIndexes, values are
+	// VlanPortTagged, VlanPortUntagged or VlanPortNone
+	SetVlanPorts(int, map[string]string) error
+	// Set port VLAN ids. Map keys are ifIndexes
+	SetPvid(map[string]int) error
+}
+
 // Functionality related to IP addresses
 type DevIpReader interface {
 	IpInfo(...string) (map[string]*IpInfo, error)
@@ -98,16 +113,65 @@ type DevBackupper interface {
 	DoBackup() error
 }
 
+// Configuration restore
+type DevRestorer interface {
+	// Restore device config from backup file and verify result.
+	// File name is path on backup target (BackupParams.TargetIp).
+	// Content is needed for verification and for embedded server (BackupParams.RecvAddr).
+	DoRestore(*BackupFile) error
+}
+
+// Get backup file received by embedded receiver
+type DevBackupFileReader interface {
+	BackupFile() *BackupFile
+}
+
 // Get environment sensors info
 type DevSensorsReader interface {
 	Sensors([]string) (map[string]map[string]map[string]SensorVal, error)
 }
 
+// Get active alarms
+type DevAlarmReader interface {
+	Alarms() ([]*AlarmInfo, error)
+}
+
+// Functionality related to climate control units
+type DevClimateWriter interface {
+	// Set unit setpoint
+	SetClimateSetpoint(string, string, float64) error
+	// Switch unit on or off
+	SetClimateUnitState(string, bool) error
+}
+
 // Get ONU info
 type DevOnusReader interface {
 	OnuInfo() (map[string]*OnuInfo, error)
 }
 
+// Get OLT PON port optical info
+type DevPonOpticsReader interface {
+	PonOptics() (map[string]*PonOptics, error)
+}
+
+// ONU provisioning
+type DevOnuWriter interface {
+	// Authorize ONU. Args: serial, name
+	AuthorizeOnu(string, string) error
+	// Remove ONU authorization
+	UnauthorizeOnu(string) error
+	// Change ONU settings. Map keys are ONU serials
+	SetOnus(map[string]*OnuSet) error
+	// Reboot ONU
+	RebootOnu(string) error
+}
+
+// ONU firmware upgrade
+type DevOnuUpgrader interface {
+	// Upgrade outdated ONUs in batches per PON port. Returns results of attempted upgrades
+	UpgradeOnus(*OnuUpgradeParams) ([]*OnuUpgradeResult, error)
+}
+
 // Get Phase Sync info
 type DevPhaseSyncReader interface {
 	PhaseSyncInfo() (*PhaseSyncInfo, error)
@@ -137,6 +201,30 @@ type DevEnergyMeterReader interface {
 type DevCliWriter interface {
 	// Execute cli commands
 	RunCmds([]string, *CliCmdOpts) ([]string, error)
+	// Execute cli commands. Returns result per command
+	RunCmdsStructured([]string, *CliCmdOpts) ([]*CliCmdResult, error)
+}
+
+// Persistent cli session functionality
+type DevCliSessManager interface {
+	// Open persistent cli session
+	OpenCli() error
+	// Close persistent cli session
+	CloseCli() error
+}
+
+// Configuration transactions
+type DevConfigurator interface {
+	// Open candidate configuration
+	CfgOpen() error
+	// Apply configuration lines. Changes will be rolled back on error
+	CfgApply([]string) error
+	// Get difference between active and candidate configuration
+	CfgDiff() (string, error)
+	// Commit candidate configuration and close transaction
+	CfgCommit() error
+	// Discard candidate configuration and close transaction
+	CfgRollback() error
 }
 
 // Get running config
@@ -144,11 +232,31 @@ type DevConfReader interface {
 	RuningCfg() (string, error)
 }
 
+// Get startup config
+type DevStartupConfReader interface {
+	StartupCfg() (string, error)
+}
+
 // Mobile signal related functionality
 type DevMobReader interface {
 	MobSignal() (map[string]MobSignal, error)
 }
 
+// Mobile SIM and data usage info. Map keys are same as MobSignal keys
+type DevMobInfoReader interface {
+	MobInfo() (map[string]*MobInfo, error)
+}
+
+// Mobile modem control. Modem ids are keys used by MobSignal
+type DevMobWriter interface {
+	// Switch active SIM. Args: modem id, SIM slot
+	SwitchMobSim(string, string) error
+	// Change SIM settings (APN, PIN)
+	SetMobSim(string, *MobSimSet) error
+	// Reset modem. Args: modem id, reset type (MobResetSession, MobResetReconnect or MobResetPower)
+	ResetMob(string, string) error
+}
+
 // Test interface
 // type DevTest interface {
 // 	TestCmd([]string) ([]string, error)
@@ -2,6 +2,9 @@ package godevman
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // Adds Juniper specific SNMP functionality to snmpCommon type
@@ -40,12 +43,38 @@ func (sd *deviceJuniper) cliPrepare() (*CliParams, error) {
 			"set cli screen-length 0",
 		}
 	}
+	if sd.cliSession.params.Interact == nil {
+		params.Interact = []CliInteract{
+			{Re: `---\(more[^)]*\)---\s*$`, Send: " ", NoLineEnd: true},
+		}
+	}
 
 	return params, nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceJuniper) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceJuniper) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceJuniper) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -55,12 +84,17 @@ func (sd *deviceJuniper) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -111,3 +145,385 @@ func (sd *deviceJuniper) SetIfAlias(set map[string]string) error {
 
 	return nil
 }
+
+// Apply VLAN configuration lines in configuration transaction
+func (sd *deviceJuniper) vlanCfg(lines []string) error {
+	if _, err := CfgTransaction(sd, lines, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Returns VLAN name used in configuration
+func junosVlanName(cur map[string]*D1qVlanInfo, vid int) (string, error) {
+	i, ok := cur[strconv.Itoa(vid)]
+	if !ok || i.Name == "" {
+		return "", fmt.Errorf("name of VLAN %d not found", vid)
+	}
+
+	return i.Name, nil
+}
+
+// Set via CLI
+// Create VLAN. VLAN name is "vlan<id>" if not defined
+func (sd *deviceJuniper) AddVlan(vid int, name string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := vlanAddCheck(cur, vid); err != nil {
+		return err
+	}
+
+	v := strconv.Itoa(vid)
+	if name == "" {
+		name = "vlan" + v
+	}
+
+	return sd.vlanCfg([]string{"set vlans " + name + " vlan-id " + v})
+}
+
+// Set via CLI
+// Remove VLAN
+func (sd *deviceJuniper) DelVlan(vid int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanDelCheck(cur, vid); err != nil {
+		return err
+	}
+
+	name, err := junosVlanName(cur, vid)
+	if err != nil {
+		return err
+	}
+
+	return sd.vlanCfg([]string{"delete vlans " + name})
+}
+
+// Set via CLI
+// Rename VLAN
+func (sd *deviceJuniper) RenameVlan(vid int, name string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := vlanExists(cur, vid); err != nil {
+		return err
+	}
+
+	old, err := junosVlanName(cur, vid)
+	if err != nil {
+		return err
+	}
+
+	return sd.vlanCfg([]string{"rename vlans " + old + " to " + name})
+}
+
+// Set via CLI
+// Set VLAN port membership. Access port is converted to trunk when tagged VLAN is added.
+// set - map of ifIndexes and their membership modes (tagged|untagged|none)
+func (sd *deviceJuniper) SetVlanPorts(vid int, set map[string]string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPortsCheck(cur, vid, set, vlanAccessMoves(cur, set)); err != nil {
+		return err
+	}
+
+	name, err := junosVlanName(cur, vid)
+	if err != nil {
+		return err
+	}
+
+	idxs := make([]string, 0, len(set))
+	for k := range set {
+		idxs = append(idxs, k)
+	}
+	sort.Strings(idxs)
+
+	names, err := sd.ifDescrs(idxs)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, i := range idxs {
+		n, _ := strconv.Atoi(i)
+		trunk := vlanTrunkPort(cur, n)
+		untag := vlanPortUntagged(cur, n)
+
+		ifc := "interfaces " + strings.TrimSuffix(names[i], ".0")
+		es := ifc + " unit 0 family ethernet-switching"
+		switch set[i] {
+		case VlanPortTagged:
+			if !trunk {
+				lines = append(lines, "set "+es+" interface-mode trunk")
+				if untag > 0 {
+					lines = append(lines, "set "+ifc+" native-vlan-id "+strconv.Itoa(untag))
+				}
+			}
+			lines = append(lines, "set "+es+" vlan members "+name)
+		case VlanPortUntagged:
+			if trunk {
+				lines = append(lines, "set "+ifc+" native-vlan-id "+strconv.Itoa(vid))
+			} else {
+				lines = append(lines, "delete "+es+" vlan members", "set "+es+" interface-mode access")
+			}
+			lines = append(lines, "set "+es+" vlan members "+name)
+		case VlanPortNone:
+			lines = append(lines, "delete "+es+" vlan members "+name)
+			if trunk && untag == vid {
+				lines = append(lines, "delete "+ifc+" native-vlan-id")
+			}
+		}
+	}
+
+	return sd.vlanCfg(lines)
+}
+
+// Set via CLI
+// Set native VLAN of trunk ports and VLAN of access ports
+// set - map of ifIndexes and their VLAN ids
+func (sd *deviceJuniper) SetPvid(set map[string]int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPvidCheck(cur, set); err != nil {
+		return err
+	}
+
+	idxs := make([]string, 0, len(set))
+	for k := range set {
+		idxs = append(idxs, k)
+	}
+	sort.Strings(idxs)
+
+	names, err := sd.ifDescrs(idxs)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, i := range idxs {
+		n, _ := strconv.Atoi(i)
+		ifc := "interfaces " + strings.TrimSuffix(names[i], ".0")
+
+		if vlanTrunkPort(cur, n) {
+			lines = append(lines, "set "+ifc+" native-vlan-id "+strconv.Itoa(set[i]))
+			continue
+		}
+
+		name, err := junosVlanName(cur, set[i])
+		if err != nil {
+			return err
+		}
+		es := ifc + " unit 0 family ethernet-switching"
+		lines = append(lines, "delete "+es+" vlan members", "set "+es+" vlan members "+name)
+	}
+
+	return sd.vlanCfg(lines)
+}
+
+// Get active alarms from .iso.org.dod.internet.private.enterprises.juniperMIB.jnxMibs.jnxAlarms.jnxCraftAlarms tree
+// Juniper alarm MIB exposes only alarm counts per severity (red - major, yellow - minor)
+func (sd *deviceJuniper) Alarms() ([]*AlarmInfo, error) {
+	var out []*AlarmInfo
+
+	oid := ".1.3.6.1.4.1.2636.3.4.2"
+	groups := map[string][]string{
+		"red":    {"3.1.0", "3.2.0", "3.3.0"},
+		"yellow": {"2.1.0", "2.2.0", "2.3.0"},
+	}
+
+	severity := map[string]string{
+		"red":    "major",
+		"yellow": "minor",
+	}
+
+	var rIdxs []string
+	for _, g := range groups {
+		rIdxs = append(rIdxs, g...)
+	}
+
+	r, err := sd.getmulti(oid, rIdxs)
+	if err != nil {
+		return out, err
+	}
+
+	sys, err := sd.System([]string{"UpTime"})
+	if err != nil {
+		return out, err
+	}
+
+	for c, g := range groups {
+		// state: 1 - other, 2 - off, 3 - on
+		if r[oid+"."+g[0]].Integer != 3 {
+			continue
+		}
+
+		cnt := r[oid+"."+g[1]].Gauge32
+		a := &AlarmInfo{
+			Source:   ValString{Value: "chassis", IsSet: true},
+			Severity: ValString{Value: severity[c], IsSet: true},
+			Text:     ValString{Value: fmt.Sprintf("%d %s alarm(s) active", cnt, c), IsSet: true},
+		}
+
+		if lc, ok := r[oid+"."+g[2]]; ok && sys.UpTime.IsSet {
+			a.setRaised(TicksTime(sys.UpTime.Value, lc.TimeTicks))
+		}
+
+		out = append(out, a)
+	}
+
+	return out, nil
+}
+
+// Open candidate configuration in private mode.
+// Opens persistent cli session which will be closed by CfgCommit or CfgRollback.
+func (sd *deviceJuniper) CfgOpen() error {
+	if err := sd.OpenCli(); err != nil {
+		return err
+	}
+
+	_, err := sd.RunCmds([]string{"configure private"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgClose(fmt.Errorf("cli command error: %v", err))
+	}
+	sd.cliSession.cfgTx = true
+
+	return nil
+}
+
+// Apply configuration lines (set/delete commands) to candidate configuration.
+// Candidate configuration will be discarded on error.
+func (sd *deviceJuniper) CfgApply(lines []string) error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	_, err := sd.RunCmds(lines, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgAbort(fmt.Errorf("cli command error: %v", err), sd.CfgRollback)
+	}
+
+	return nil
+}
+
+// Get difference between active and candidate configuration
+func (sd *deviceJuniper) CfgDiff() (string, error) {
+	if err := sd.cfgChkOpen(); err != nil {
+		return "", err
+	}
+
+	res, err := sd.RunCmdsStructured([]string{"show | compare"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return "", fmt.Errorf("cli command error: %v", err)
+	}
+
+	out := strings.TrimSpace(res[0].Output)
+	out = strings.TrimSpace(strings.TrimSuffix(out, "[edit]"))
+
+	return out, nil
+}
+
+// Commit candidate configuration using commit confirmed and close transaction.
+// Commit is confirmed only if device is reachable via new cli session after commit.
+// Candidate configuration will be discarded on error. Device rolls back
+// unconfirmed commit automatically after 5 minutes.
+func (sd *deviceJuniper) CfgCommit() error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	_, err := sd.RunCmds([]string{"commit check", "commit confirmed 5"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgAbort(fmt.Errorf("cli command error: %v", err), sd.CfgRollback)
+	}
+
+	p, err := sd.cliPrepare()
+	if err == nil {
+		err = sd.cliProbe(p, []string{"show system uptime", "exit"})
+	}
+	if err != nil {
+		return sd.cfgClose(fmt.Errorf("device check after commit confirmed failed, commit will be rolled back by device: %v", err))
+	}
+
+	_, err = sd.RunCmds([]string{"commit and-quit"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgClose(fmt.Errorf("confirm commit failed, commit will be rolled back by device: %v", err))
+	}
+
+	return sd.cfgClose(nil)
+}
+
+// Discard candidate configuration and close transaction
+func (sd *deviceJuniper) CfgRollback() error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	_, err := sd.RunCmds([]string{"rollback 0", "exit configuration-mode"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		err = fmt.Errorf("cli command error: %v", err)
+	}
+
+	return sd.cfgClose(err)
+}
+
+// Get running config as set commands
+func (sd *deviceJuniper) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"show configuration | display set | no-more"}, "exit")
+}
+
+// Backup active config to scp server (file copy)
+func (sd *deviceJuniper) DoBackup() error {
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+	user, pass := sd.backupCred()
+	targetFile, t := sd.backupTarget(".conf.gz")
+
+	if err := sd.backupRecvDefPort("22"); err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	// Start embedded receiver if requested
+	rcv, err := sd.backupRecvStart("scp")
+	if err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	opts := &CliCmdOpts{
+		ChkErr: true,
+		Interact: []CliInteract{
+			{Re: `\(yes/no(/\[fingerprint\])?\)\?\s*$`, Send: "yes"},
+			{Re: `(?i)'s password:\s*$`, Send: pass},
+		},
+	}
+
+	cmds := []string{"file copy /config/juniper.conf.gz " + user + "@" + host + ":" + targetFile}
+	_, err = sd.RunCmdsStructured(sd.cliExit(cmds, "exit"), opts)
+	if err != nil {
+		if rcv != nil {
+			rcv.stop()
+		}
+		return sd.backupDone(host, targetFile, t, fmt.Errorf("cli error: %v", err))
+	}
+
+	if rcv != nil {
+		err = sd.backupRecvFinish(rcv, targetFile)
+	}
+
+	return sd.backupDone(host, targetFile, t, err)
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceJuniper) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
+}
@@ -56,8 +56,29 @@ func (sd *deviceLinux) BuildOpts() (string, error) {
 	return r[oid].OctetString, err
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceLinux) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceLinux) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceLinux) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -67,12 +88,17 @@ func (sd *deviceLinux) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -88,3 +114,28 @@ func (sd *deviceLinux) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 
 	return out, nil
 }
+
+// Configuration files of linux based devices
+var linuxCfgFiles = []string{
+	"/etc/hostname",
+	"/etc/hosts",
+	"/etc/resolv.conf",
+	"/etc/network/interfaces",
+	"/etc/ntp.conf",
+	"/etc/crontab",
+}
+
+// Returns cli commands for printing configuration files with file name header
+func linuxCfgCmds(files []string) []string {
+	var cmds []string
+	for _, f := range files {
+		cmds = append(cmds, "echo '### "+f+"'; cat "+f+" 2>/dev/null")
+	}
+
+	return cmds
+}
+
+// Get running config (content of main configuration files)
+func (sd *deviceLinux) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, linuxCfgCmds(linuxCfgFiles), "exit")
+}
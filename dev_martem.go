@@ -100,8 +100,221 @@ func (sd *deviceMartem) MobSignal() (map[string]MobSignal, error) {
 	return ret, nil
 }
 
+// Returns shell quoted string
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Check if modem exists. Martem devices have single modem managed by ModemManager,
+// so modem id is not used for modem selection in CLI commands.
+func (sd *deviceMartem) mobCheck(id string) error {
+	r, err := sd.MobSignal()
+	if err != nil {
+		return err
+	}
+	if _, ok := r[id]; !ok {
+		return fmt.Errorf("modem %s not found", id)
+	}
+
+	return nil
+}
+
+// Get info from CLI (ModemManager)
+// Returns mobile SIM info. Data counters are not supported (not set).
+func (sd *deviceMartem) MobInfo() (map[string]*MobInfo, error) {
+	out := make(map[string]*MobInfo)
+
+	sig, err := sd.MobSignal()
+	if err != nil {
+		return out, err
+	}
+	if len(sig) == 0 {
+		return out, nil
+	}
+
+	cmds := []string{"mmcli -m any -K", "mmcli -i any -K"}
+	res, err := sd.RunCmdsStructured(sd.cliExit(cmds, "exit"), nil)
+	if err != nil {
+		return out, fmt.Errorf("cli command error: %v", err)
+	}
+	if len(res) < len(cmds) {
+		return out, fmt.Errorf("unexpected cli output")
+	}
+
+	info := colonParser(res[0].Output)
+	for k, v := range colonParser(res[1].Output) {
+		info[k] = v
+	}
+
+	m := new(MobInfo)
+	set := func(v *ValString, k string) {
+		if s, ok := info[k]; ok && s != "--" {
+			v.Value = s
+			v.IsSet = true
+		}
+	}
+	set(&m.Imsi, "sim.properties.imsi")
+	set(&m.Iccid, "sim.properties.iccid")
+	set(&m.Msisdn, "modem.generic.own-numbers.value[1]")
+	// SIM state: "missing", "active" or "inactive"
+	switch {
+	case info["modem.generic.state-failed-reason"] == "sim-missing":
+		m.SimState = ValString{Value: "missing", IsSet: true}
+	case info["sim.properties.active"] == "yes":
+		m.SimState = ValString{Value: "active", IsSet: true}
+	case info["sim.properties.active"] == "no":
+		m.SimState = ValString{Value: "inactive", IsSet: true}
+	}
+	set(&m.Operator, "modem.3gpp.operator-name")
+	set(&m.ActiveSim, "modem.generic.primary-sim-slot")
+	if s, ok := info["modem.generic.unlock-required"]; ok {
+		m.PinState.Value = s
+		if s == "--" {
+			m.PinState.Value = "none"
+		}
+		m.PinState.IsSet = true
+	}
+	if s, ok := info["modem.3gpp.registration-state"]; ok {
+		m.Roaming.Value = s == "roaming"
+		m.Roaming.IsSet = true
+	}
+
+	// Martem devices have single modem
+	for id := range sig {
+		out[id] = m
+	}
+
+	return out, nil
+}
+
+// Shell expression which returns name of mobile NetworkManager connection
+const martemGsmCon = `"$(nmcli -g NAME,TYPE connection show | grep ':gsm$' | head -n1 | cut -d: -f1)"`
+
+// Set via CLI
+// Switch active SIM slot (fe. "1")
+func (sd *deviceMartem) SwitchMobSim(id, slot string) error {
+	if err := sd.mobCheck(id); err != nil {
+		return err
+	}
+	if slot == "" || strings.Trim(slot, "0123456789") != "" {
+		return fmt.Errorf("not valid SIM slot: %s", slot)
+	}
+
+	cmds := []string{"mmcli -m any --set-primary-sim-slot=" + slot}
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "exit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Change APN and PIN of mobile connection. Only active SIM can be changed.
+func (sd *deviceMartem) SetMobSim(id string, s *MobSimSet) error {
+	if err := mobSimCheck(s); err != nil {
+		return err
+	}
+	if s.Slot != "" {
+		return fmt.Errorf("per slot SIM settings are not supported")
+	}
+	if err := sd.mobCheck(id); err != nil {
+		return err
+	}
+
+	var set []string
+	if s.Apn != nil {
+		set = append(set, "gsm.apn "+shellQuote(*s.Apn))
+	}
+	if s.ApnUser != nil {
+		set = append(set, "gsm.username "+shellQuote(*s.ApnUser))
+	}
+	if s.ApnPass != nil {
+		set = append(set, "gsm.password "+shellQuote(*s.ApnPass))
+	}
+	if s.ApnAuth != nil {
+		switch *s.ApnAuth {
+		case "none":
+			set = append(set, "ppp.refuse-pap no ppp.refuse-chap no")
+		case "pap":
+			set = append(set, "ppp.refuse-pap no ppp.refuse-chap yes")
+		case "chap":
+			set = append(set, "ppp.refuse-pap yes ppp.refuse-chap no")
+		}
+	}
+	if s.Pin != nil {
+		set = append(set, "gsm.pin "+shellQuote(*s.Pin))
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	cmds := []string{
+		"nmcli connection modify " + martemGsmCon + " " + strings.Join(set, " "),
+		"nmcli connection up " + martemGsmCon,
+	}
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "exit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Reset mobile modem
+func (sd *deviceMartem) ResetMob(id, kind string) error {
+	if err := mobResetCheck(kind); err != nil {
+		return err
+	}
+	if err := sd.mobCheck(id); err != nil {
+		return err
+	}
+
+	var cmds []string
+	switch kind {
+	case MobResetSession:
+		cmds = []string{
+			"nmcli connection down " + martemGsmCon,
+			"nmcli connection up " + martemGsmCon,
+		}
+	case MobResetReconnect:
+		cmds = []string{
+			"mmcli -m any --disable",
+			"mmcli -m any --enable",
+		}
+	case MobResetPower:
+		cmds = []string{"mmcli -m any --reset"}
+	}
+
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "exit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceMartem) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceMartem) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceMartem) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -111,12 +324,17 @@ func (sd *deviceMartem) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -3,6 +3,7 @@ package godevman
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -119,6 +120,215 @@ func (sd *deviceMikrotik) MobSignal() (map[string]MobSignal, error) {
 	return ret, nil
 }
 
+// Returns quoted CLI string value
+func mikrotikQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// Returns name of LTE interface. id - ifIndex of interface (MobSignal key)
+func (sd *deviceMikrotik) lteIfName(id string) (string, error) {
+	r, err := sd.IfInfo([]string{"Descr"}, id)
+	if err != nil {
+		return "", fmt.Errorf("ifinfo error: %v", err)
+	}
+
+	i, ok := r[id]
+	if !ok || !i.Descr.IsSet || i.Descr.Value == "" {
+		return "", fmt.Errorf("modem %s not found", id)
+	}
+
+	return i.Descr.Value, nil
+}
+
+// Get info from CLI
+// Returns mobile SIM info of LTE interfaces. Map keys are ifIndexes of LTE interfaces.
+// Msisdn is set if modem reports it. Data counters are not supported (not set).
+func (sd *deviceMikrotik) MobInfo() (map[string]*MobInfo, error) {
+	out := make(map[string]*MobInfo)
+
+	sig, err := sd.MobSignal()
+	if err != nil {
+		return out, err
+	}
+
+	ids := make([]string, 0, len(sig))
+	cmds := []string{"/system routerboard sim print"}
+	for id := range sig {
+		name, err := sd.lteIfName(id)
+		if err != nil {
+			return out, err
+		}
+		ids = append(ids, id)
+		cmds = append(cmds, "/interface lte info [find name="+mikrotikQuote(name)+"] once")
+	}
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	res, err := sd.RunCmdsStructured(sd.cliExit(cmds, "/quit"), nil)
+	if err != nil {
+		return out, fmt.Errorf("cli command error: %v", err)
+	}
+	if len(res) < len(cmds) {
+		return out, fmt.Errorf("unexpected cli output")
+	}
+
+	// Boards without SIM slot switch don't support sim menu
+	slot := colonParser(res[0].Output)["sim-slot"]
+
+	for i, id := range ids {
+		info := colonParser(res[i+1].Output)
+		m := new(MobInfo)
+
+		set := func(v *ValString, k string) {
+			if s, ok := info[k]; ok {
+				v.Value = s
+				v.IsSet = true
+			}
+		}
+		set(&m.Imsi, "imsi")
+		set(&m.Iccid, "uicc")
+		set(&m.Msisdn, "msisdn")
+		set(&m.PinState, "pin-status")
+		set(&m.Operator, "current-operator")
+		if s, ok := info["registration-status"]; ok {
+			m.SimState.Value = s
+			m.SimState.IsSet = true
+			m.Roaming.Value = s == "roaming"
+			m.Roaming.IsSet = true
+		}
+		if slot != "" {
+			m.ActiveSim.Value = slot
+			m.ActiveSim.IsSet = true
+		}
+
+		out[id] = m
+	}
+
+	return out, nil
+}
+
+// Set via CLI
+// Switch active SIM slot ("a", "b", "up", "down" or "1", "2") and restart LTE interface.
+// id - ifIndex of LTE interface
+func (sd *deviceMikrotik) SwitchMobSim(id, slot string) error {
+	name, err := sd.lteIfName(id)
+	if err != nil {
+		return err
+	}
+
+	switch slot {
+	case "1":
+		slot = "a"
+	case "2":
+		slot = "b"
+	case "a", "b", "up", "down":
+	default:
+		return fmt.Errorf("not valid SIM slot: %s", slot)
+	}
+
+	iface := "[find name=" + mikrotikQuote(name) + "]"
+	cmds := []string{
+		"/system routerboard sim set sim-slot=" + slot,
+		"/interface lte disable " + iface,
+		"/interface lte enable " + iface,
+	}
+
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Change APN profile and PIN of LTE interface. Only active SIM can be changed.
+// id - ifIndex of LTE interface
+func (sd *deviceMikrotik) SetMobSim(id string, s *MobSimSet) error {
+	if err := mobSimCheck(s); err != nil {
+		return err
+	}
+	if s.Slot != "" {
+		return fmt.Errorf("per slot SIM settings are not supported")
+	}
+
+	name, err := sd.lteIfName(id)
+	if err != nil {
+		return err
+	}
+	iface := "[find name=" + mikrotikQuote(name) + "]"
+
+	var apn []string
+	if s.Apn != nil {
+		apn = append(apn, "apn="+mikrotikQuote(*s.Apn))
+	}
+	if s.ApnUser != nil {
+		apn = append(apn, "user="+mikrotikQuote(*s.ApnUser))
+	}
+	if s.ApnPass != nil {
+		apn = append(apn, "password="+mikrotikQuote(*s.ApnPass))
+	}
+	if s.ApnAuth != nil {
+		apn = append(apn, "authentication="+*s.ApnAuth)
+	}
+
+	var cmds []string
+	if len(apn) > 0 {
+		cmds = append(cmds, "/interface lte apn set [find name=[/interface lte get "+iface+" apn-profiles]] "+
+			strings.Join(apn, " "))
+	}
+	if s.Pin != nil {
+		cmds = append(cmds, "/interface lte set "+iface+" pin="+mikrotikQuote(*s.Pin))
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Reset LTE modem. id - ifIndex of LTE interface
+func (sd *deviceMikrotik) ResetMob(id, kind string) error {
+	if err := mobResetCheck(kind); err != nil {
+		return err
+	}
+
+	name, err := sd.lteIfName(id)
+	if err != nil {
+		return err
+	}
+	iface := "[find name=" + mikrotikQuote(name) + "]"
+
+	var cmds []string
+	switch kind {
+	case MobResetSession:
+		cmds = []string{
+			"/interface lte disable " + iface,
+			"/interface lte enable " + iface,
+		}
+	case MobResetReconnect:
+		cmds = []string{
+			"/interface lte at-chat " + iface + " input=\"AT+CFUN=4\"",
+			":delay 2s",
+			"/interface lte at-chat " + iface + " input=\"AT+CFUN=1\"",
+		}
+	case MobResetPower:
+		cmds = []string{"/system routerboard usb power-reset duration=5s"}
+	}
+
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
 // Prepare CLI session parameters
 func (sd *deviceMikrotik) cliPrepare() (*CliParams, error) {
 	defParams, err := sd.snmpCommon.cliPrepare()
@@ -131,7 +341,7 @@ func (sd *deviceMikrotik) cliPrepare() (*CliParams, error) {
 	// make device specific changes to default parameters
 	params.Cred[0] = params.Cred[0] + "+ct600w"
 	if sd.cliSession.params.PromptRe == "" {
-		params.PromptRe = `\] (\/.+)?>\s+$`
+		params.PromptRe = `\] (\/.+)?(<SAFE)?>\s+$`
 	}
 	if sd.cliSession.params.ErrRe == "" {
 		params.ErrRe = `(?im)(failure|error|unknown|unrecognized|invalid|not recognized|examples:|bad command)`
@@ -143,8 +353,29 @@ func (sd *deviceMikrotik) cliPrepare() (*CliParams, error) {
 	return params, nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceMikrotik) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceMikrotik) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceMikrotik) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -154,12 +385,17 @@ func (sd *deviceMikrotik) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		return out, err
 	}
@@ -357,6 +593,326 @@ func (sd *deviceMikrotik) SetIfAlias(set map[string]string) error {
 	return nil
 }
 
+// Returns VLAN ids of Mikrotik VLAN id list (fe. "10,20,30-40")
+func mikrotikVlanIds(s string) []int {
+	var out []int
+	for _, p := range strings.Split(s, ",") {
+		r := strings.SplitN(p, "-", 2)
+		from, err := strconv.Atoi(r[0])
+		if err != nil {
+			continue
+		}
+
+		to := from
+		if len(r) == 2 {
+			if to, err = strconv.Atoi(r[1]); err != nil {
+				continue
+			}
+		}
+
+		for v := from; v <= to && v <= 4094; v++ {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// Returns " param=value" list of parameters for cli command
+func mikrotikParams(p map[string]string) string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out string
+	for _, k := range keys {
+		out += " " + k + "=" + mikrotikQuote(p[k])
+	}
+
+	return out
+}
+
+// Mikrotik bridge VLAN entry
+type mikrotikBrVlan struct {
+	// entry parameters
+	params map[string]string
+	// VLAN ids of entry
+	vids []int
+}
+
+// Returns find expression of entry
+func (e *mikrotikBrVlan) find() string {
+	return "[find bridge=" + mikrotikQuote(e.params["bridge"]) + " vlan-ids=" + e.params["vlan-ids"] + "]"
+}
+
+// Returns VLAN ids of entry except submitted VLAN
+func (e *mikrotikBrVlan) rest(vid int) string {
+	var ids []string
+	for _, v := range e.vids {
+		if v != vid {
+			ids = append(ids, strconv.Itoa(v))
+		}
+	}
+
+	return strings.Join(ids, ",")
+}
+
+// Returns commands which set parameters of single VLAN of entry.
+// VLAN is moved to separate entry if entry is shared with other VLANs.
+func (e *mikrotikBrVlan) setCmds(vid int, set map[string]string) []string {
+	rest := e.rest(vid)
+	if rest == "" {
+		return []string{"/interface bridge vlan set " + e.find() + mikrotikParams(set)}
+	}
+
+	p := make(map[string]string)
+	for _, k := range []string{"tagged", "untagged", "comment"} {
+		if v, ok := e.params[k]; ok {
+			p[k] = v
+		}
+	}
+	for k, v := range set {
+		p[k] = v
+	}
+	p["bridge"] = e.params["bridge"]
+	p["vlan-ids"] = strconv.Itoa(vid)
+
+	return []string{
+		"/interface bridge vlan set " + e.find() + " vlan-ids=" + rest,
+		"/interface bridge vlan add" + mikrotikParams(p),
+	}
+}
+
+// Returns commands which remove single VLAN of entry
+func (e *mikrotikBrVlan) delCmds(vid int) []string {
+	if rest := e.rest(vid); rest != "" {
+		return []string{"/interface bridge vlan set " + e.find() + " vlan-ids=" + rest}
+	}
+
+	return []string{"/interface bridge vlan remove " + e.find()}
+}
+
+// Returns bridge VLAN entry of VLAN
+func (sd *deviceMikrotik) bridgeVlan(vid int) (*mikrotikBrVlan, error) {
+	info, err := sd.vlanInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range info[strconv.Itoa(vid)] {
+		if _, ok := e["bridge"]; ok {
+			return &mikrotikBrVlan{params: e, vids: mikrotikVlanIds(e["vlan-ids"])}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("bridge VLAN %d not found", vid)
+}
+
+// Returns VLAN filtering bridge for new VLAN.
+// Bridge of management VLAN is used if there are several VLAN filtering bridges.
+func (sd *deviceMikrotik) vlanBridge() (string, error) {
+	cmds := []string{"/interface bridge print terse where vlan-filtering=yes"}
+	res, err := sd.RunCmdsStructured(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return "", fmt.Errorf("cli command error: %v", err)
+	}
+
+	var bridges []string
+	for _, r := range res[:len(cmds)] {
+		for _, row := range SplitLineEnd(r.Output) {
+			if n, ok := sd.terseParser(row)["name"]; ok {
+				bridges = append(bridges, n)
+			}
+		}
+	}
+
+	switch len(bridges) {
+	case 0:
+		return "", fmt.Errorf("VLAN filtering bridge not found")
+	case 1:
+		return bridges[0], nil
+	}
+
+	if e, err := sd.bridgeVlan(sd.mgmtVlan); err == nil {
+		return e.params["bridge"], nil
+	}
+
+	return "", fmt.Errorf("several VLAN filtering bridges found: %s", strings.Join(bridges, ", "))
+}
+
+// Set via CLI
+// Create bridge VLAN on VLAN filtering bridge. Name is stored as comment.
+func (sd *deviceMikrotik) AddVlan(vid int, name string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := vlanAddCheck(cur, vid); err != nil {
+		return err
+	}
+
+	bridge, err := sd.vlanBridge()
+	if err != nil {
+		return err
+	}
+
+	p := map[string]string{"bridge": bridge, "vlan-ids": strconv.Itoa(vid)}
+	if name != "" {
+		p["comment"] = name
+	}
+
+	cmd := "/interface bridge vlan add" + mikrotikParams(p)
+	if _, err := sd.RunCmds(sd.cliExit([]string{cmd}, "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Remove bridge VLAN. VLAN is removed from VLAN id list of entry if entry is shared with other VLANs.
+func (sd *deviceMikrotik) DelVlan(vid int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanDelCheck(cur, vid); err != nil {
+		return err
+	}
+
+	e, err := sd.bridgeVlan(vid)
+	if err != nil {
+		return err
+	}
+
+	if _, err := sd.RunCmds(sd.cliExit(e.delCmds(vid), "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Set bridge VLAN comment
+func (sd *deviceMikrotik) RenameVlan(vid int, name string) error {
+	if err := vlanIdCheck(vid); err != nil {
+		return err
+	}
+
+	e, err := sd.bridgeVlan(vid)
+	if err != nil {
+		return err
+	}
+
+	cmds := e.setCmds(vid, map[string]string{"comment": name})
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Set bridge VLAN tagged and untagged ports
+// set - map of ifIndexes and their membership modes (tagged|untagged|none)
+func (sd *deviceMikrotik) SetVlanPorts(vid int, set map[string]string) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPortsCheck(cur, vid, set, nil); err != nil {
+		return err
+	}
+
+	e, err := sd.bridgeVlan(vid)
+	if err != nil {
+		return err
+	}
+
+	idxs := make([]string, 0, len(set))
+	for k := range set {
+		idxs = append(idxs, k)
+	}
+	sort.Strings(idxs)
+
+	names, err := sd.ifDescrs(idxs)
+	if err != nil {
+		return err
+	}
+
+	ports := make(map[string][]string)
+	for _, m := range []string{VlanPortTagged, VlanPortUntagged} {
+		for _, p := range strings.Split(e.params[m], ",") {
+			if p != "" {
+				ports[m] = append(ports[m], p)
+			}
+		}
+	}
+
+	for _, i := range idxs {
+		n := names[i]
+		for m, l := range ports {
+			var keep []string
+			for _, p := range l {
+				if p != n {
+					keep = append(keep, p)
+				}
+			}
+			ports[m] = keep
+		}
+		if m := set[i]; m != VlanPortNone {
+			ports[m] = append(ports[m], n)
+		}
+	}
+
+	cmds := e.setCmds(vid, map[string]string{
+		"tagged":   strings.Join(ports[VlanPortTagged], ","),
+		"untagged": strings.Join(ports[VlanPortUntagged], ","),
+	})
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
+// Set via CLI
+// Set bridge port VLAN ids
+// set - map of ifIndexes and their VLAN ids
+func (sd *deviceMikrotik) SetPvid(set map[string]int) error {
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPvidCheck(cur, set); err != nil {
+		return err
+	}
+
+	idxs := make([]string, 0, len(set))
+	for k := range set {
+		idxs = append(idxs, k)
+	}
+	sort.Strings(idxs)
+
+	names, err := sd.ifDescrs(idxs)
+	if err != nil {
+		return err
+	}
+
+	cmds := []string{"/interface bridge port"}
+	for _, i := range idxs {
+		cmds = append(cmds, "set [find interface="+mikrotikQuote(names[i])+"] pvid="+strconv.Itoa(set[i]))
+	}
+
+	if _, err := sd.RunCmds(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true}); err != nil {
+		return fmt.Errorf("cli command error: %v", err)
+	}
+
+	return nil
+}
+
 // Get info from CLI
 // Returns vlan info
 func (sd *deviceMikrotik) vlanInfo() (map[string][]map[string]string, error) {
@@ -367,10 +923,9 @@ func (sd *deviceMikrotik) vlanInfo() (map[string][]map[string]string, error) {
 		"/interface ethernet switch vlan print terse detail",
 		"/interface bridge vlan print terse detail",
 		"/interface ethernet switch egress-vlan-tag print terse detail",
-		"/quit",
 	}
 
-	r, err := sd.RunCmds(cmds, nil)
+	r, err := sd.RunCmds(sd.cliExit(cmds, "/quit"), nil)
 	if err != nil {
 		return vlans, fmt.Errorf("cli command error: %v", err)
 	}
@@ -393,8 +948,12 @@ func (sd *deviceMikrotik) vlanInfo() (map[string][]map[string]string, error) {
 		if vlan, ok := params["vlan-id"]; ok {
 			vlans[vlan] = append(vlans[vlan], params)
 		}
-		if vlan, ok := params["vlan-ids"]; ok {
-			vlans[vlan] = append(vlans[vlan], params)
+		// Bridge VLAN entry can contain list of VLAN ids
+		if ids, ok := params["vlan-ids"]; ok {
+			for _, v := range mikrotikVlanIds(ids) {
+				vlan := strconv.Itoa(v)
+				vlans[vlan] = append(vlans[vlan], params)
+			}
 		}
 	}
 
@@ -418,3 +977,219 @@ func (sd *deviceMikrotik) terseParser(row string) map[string]string {
 
 	return out
 }
+
+// Get configuration export lines without comments
+func (sd *deviceMikrotik) cfgExport() ([]string, error) {
+	res, err := sd.RunCmdsStructured([]string{"/export"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return nil, fmt.Errorf("cli command error: %v", err)
+	}
+
+	var out []string
+	for _, l := range SplitLineEnd(res[0].Output) {
+		if strings.HasPrefix(l, "#") || strings.TrimSpace(l) == "" {
+			continue
+		}
+		out = append(out, l)
+	}
+
+	return out, nil
+}
+
+// Open configuration transaction in safe mode.
+// Mikrotik has no candidate configuration, changes are applied immediately
+// and undone by device if safe mode session terminates abnormally.
+// Opens persistent cli session which will be closed by CfgCommit or CfgRollback.
+func (sd *deviceMikrotik) CfgOpen() error {
+	if err := sd.OpenCli(); err != nil {
+		return err
+	}
+
+	base, err := sd.cfgExport()
+	if err != nil {
+		return sd.cfgClose(err)
+	}
+
+	// Ctrl-X toggles safe mode
+	res, err := sd.RunCmdsStructured([]string{"\x18"}, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgClose(fmt.Errorf("cli command error: %v", err))
+	}
+	if !strings.Contains(res[0].Prompt, "<SAFE") {
+		return sd.cfgClose(fmt.Errorf("enter safe mode failed: %s", res[0].Output))
+	}
+
+	sd.cliSession.cfgTx = true
+	sd.cliSession.cfgBase = base
+
+	return nil
+}
+
+// Apply configuration lines in safe mode.
+// Changes will be undone on error.
+func (sd *deviceMikrotik) CfgApply(lines []string) error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	_, err := sd.RunCmds(lines, &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.cfgAbort(fmt.Errorf("cli command error: %v", err), sd.CfgRollback)
+	}
+
+	return nil
+}
+
+// Get difference between configuration exports at the start of transaction and current
+func (sd *deviceMikrotik) CfgDiff() (string, error) {
+	if err := sd.cfgChkOpen(); err != nil {
+		return "", err
+	}
+
+	cur, err := sd.cfgExport()
+	if err != nil {
+		return "", err
+	}
+
+	return DiffChanges(sd.cliSession.cfgBase, cur), nil
+}
+
+// Leave safe mode keeping changes and close transaction
+func (sd *deviceMikrotik) CfgCommit() error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	res, err := sd.RunCmdsStructured([]string{"\x18"}, &CliCmdOpts{ChkErr: true})
+	if err == nil && strings.Contains(res[0].Prompt, "<SAFE") {
+		err = fmt.Errorf("leave safe mode failed: %s", res[0].Output)
+	}
+	if err != nil {
+		return sd.cfgAbort(fmt.Errorf("cli command error: %v", err), sd.CfgRollback)
+	}
+
+	return sd.cfgClose(nil)
+}
+
+// Undo changes by terminating safe mode session abnormally and close transaction
+func (sd *deviceMikrotik) CfgRollback() error {
+	if err := sd.cfgChkOpen(); err != nil {
+		return err
+	}
+
+	s := sd.cliSession
+	s.mu.Lock()
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+	s.unlock()
+
+	return sd.cfgClose(nil)
+}
+
+// Get running config
+func (sd *deviceMikrotik) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"/export"}, "/quit")
+}
+
+// Get sftp session parameters (login without cli console options)
+func (sd *deviceMikrotik) sftpParams() (*CliParams, error) {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return nil, err
+	}
+
+	p.Cred = append([]string{strings.SplitN(p.Cred[0], "+", 2)[0]}, p.Cred[1:]...)
+
+	return p, nil
+}
+
+// Name of temporary backup files on device
+const mikrotikBackupName = "godevman-backup"
+
+// Make binary backup (/system backup save) and configuration export (/export)
+// and fetch them from device via sftp. Files are stored via BackupParams.Store
+// and written to BackupParams.LocalDir directory if it's set.
+func (sd *deviceMikrotik) DoBackup() error {
+	if sd.backupParams == nil {
+		return fmt.Errorf("device backup parameters are not defined")
+	}
+	targetFile, t := sd.backupTarget(".backup")
+
+	cmds := []string{
+		"/system backup save name=" + mikrotikBackupName + " dont-encrypt=yes",
+		"/export file=" + mikrotikBackupName,
+	}
+	_, err := sd.RunCmdsStructured(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		return sd.backupDone("", targetFile, t, fmt.Errorf("cli error: %v", err))
+	}
+
+	p, err := sd.sftpParams()
+	if err != nil {
+		return sd.backupDone("", targetFile, t, err)
+	}
+
+	files, err := sd.sftpFetch(p, mikrotikBackupName+".rsc", mikrotikBackupName+".backup")
+	if err == nil {
+		err = sd.backupSave(files[0], strings.TrimSuffix(targetFile, ".backup")+".rsc")
+	}
+	if err == nil {
+		err = sd.backupSave(files[1], targetFile)
+	}
+
+	// Remove temporary files from device
+	cmds = []string{`/file remove [find where name~"^` + mikrotikBackupName + `\\."]`}
+	if _, err2 := sd.RunCmdsStructured(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true}); err2 != nil && err == nil {
+		err = fmt.Errorf("remove backup files from device failed: %v", err2)
+	}
+
+	return sd.backupDone("", targetFile, t, err)
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceMikrotik) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
+}
+
+// Upload configuration script (export) to device via sftp, run it (/import) and verify result.
+// Restore file content is required, file name is not used.
+func (sd *deviceMikrotik) DoRestore(f *BackupFile) error {
+	if err := sd.restoreCheck(f); err != nil {
+		return err
+	}
+	if len(f.Data) == 0 {
+		return fmt.Errorf("restore file content is not defined")
+	}
+
+	p, err := sd.sftpParams()
+	if err != nil {
+		return err
+	}
+
+	name := mikrotikBackupName + ".rsc"
+	if err := sd.sftpPut(p, name, f.Data); err != nil {
+		return err
+	}
+
+	cmds := []string{
+		"/import file-name=" + name,
+		"/file remove " + name,
+	}
+	res, err := sd.RunCmdsStructured(sd.cliExit(cmds, "/quit"), &CliCmdOpts{ChkErr: true})
+	if err == nil && !strings.Contains(res[0].Output, "executed successfully") {
+		err = fmt.Errorf("no confirm for import success, output: %s", res[0].Output)
+	}
+	if err != nil {
+		return fmt.Errorf("cli error: %v", err)
+	}
+
+	cfg, err := sd.RuningCfg()
+	if err != nil {
+		return fmt.Errorf("restore verification failed: %v", err)
+	}
+
+	// Comments are not part of configuration
+	return sd.restoreVerify(cfg, f, `^\s*#`)
+}
@@ -3,6 +3,7 @@ package godevman
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // Adds Moxa specific SNMP functionality to snmpCommon type
@@ -35,12 +36,39 @@ func (sd *deviceMoxa) cliPrepare() (*CliParams, error) {
 			"terminal length 0",
 		}
 	}
+	if sd.cliSession.params.Interact == nil {
+		params.Interact = []CliInteract{
+			{Re: `--More--\s*$`, Send: " ", NoLineEnd: true},
+			{Re: `(?i)press any key[^\r\n]*$`, Send: " ", NoLineEnd: true},
+		}
+	}
 
 	return params, nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceMoxa) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceMoxa) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceMoxa) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -50,12 +78,17 @@ func (sd *deviceMoxa) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -91,5 +124,44 @@ func (sd *deviceMoxa) RuningCfg() (string, error) {
 		return "", fmt.Errorf("can't find config from 'sho run' output")
 	}
 
-	return m[1], nil
+	return NormalizeCfg(m[1])
+}
+
+// Backup running config to tftp server
+func (sd *deviceMoxa) DoBackup() error {
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+	targetFile, t := sd.backupTarget(".ini")
+
+	if err := sd.backupRecvDefPort("69"); err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	// Start embedded receiver if requested
+	rcv, err := sd.backupRecvStart("tftp")
+	if err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	cmds := []string{"copy running-config tftp tftp://" + host + "/" + strings.TrimPrefix(targetFile, "/")}
+	_, err = sd.RunCmdsStructured(sd.cliExit(cmds, "exit"), &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		if rcv != nil {
+			rcv.stop()
+		}
+		return sd.backupDone(host, targetFile, t, fmt.Errorf("cli error: %v", err))
+	}
+
+	if rcv != nil {
+		err = sd.backupRecvFinish(rcv, targetFile)
+	}
+
+	return sd.backupDone(host, targetFile, t, err)
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceMoxa) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
 }
@@ -1,5 +1,11 @@
 package godevman
 
+import (
+	"strings"
+
+	"github.com/aretaja/snmphelper"
+)
+
 // Adds Rittal specific SNMP functionality to snmpCommon type
 type deviceRittal struct {
 	snmpCommon
@@ -11,3 +17,88 @@ func (sd *deviceRittal) SwVersion() (string, error) {
 	r, err := sd.getone(oid)
 	return r[oid].OctetString, err
 }
+
+// Get info from .iso.org.dod.internet.private.enterprises.rittal.cmcIII.cmcIIIObjects.cmcIIIDevices tree
+// Sensor units are returned as groups under "Unit" key. Group name is device name and sensor name is
+// variable name (fe. "Temperature.Value")
+// Valid targets values: "All", "Unit"
+func (sd *deviceRittal) Sensors(targets []string) (map[string]map[string]map[string]SensorVal, error) {
+	out := make(map[string]map[string]map[string]SensorVal)
+
+	devNameOid := ".1.3.6.1.4.1.2606.7.4.1.2.1.2"
+	varTable := ".1.3.6.1.4.1.2606.7.4.2.2.1."
+	varOids := map[string]string{
+		"name":     varTable + "3",
+		"unit":     varTable + "5",
+		"scale":    varTable + "7",
+		"valueStr": varTable + "10",
+		"valueInt": varTable + "11",
+	}
+
+	t, err := sensorTargets(targets, "Unit")
+	if err != nil || !t["Unit"] {
+		return out, err
+	}
+
+	devNames, err := sd.snmpSession.Walk(devNameOid, true, true)
+	if err != nil && sd.handleErr(err) {
+		return out, err
+	}
+
+	r := make(map[string]snmphelper.SnmpOut)
+	for n, o := range varOids {
+		vr, err := sd.snmpSession.Walk(o, true, true)
+		if err != nil && sd.handleErr(err) {
+			return out, err
+		}
+		r[n] = vr
+	}
+
+	out["Unit"] = make(map[string]map[string]SensorVal)
+	for idx, n := range r["name"] {
+		// index is <device index>.<variable index>
+		parts := strings.SplitN(idx, ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		dName := "Device" + parts[0]
+		if d, ok := devNames[parts[0]]; ok && d.OctetString != "" {
+			dName = d.OctetString
+		}
+
+		if out["Unit"][dName] == nil {
+			out["Unit"][dName] = make(map[string]SensorVal)
+		}
+
+		v := SensorVal{
+			Unit:  strings.TrimSpace(r["unit"][idx].OctetString),
+			IsSet: true,
+		}
+
+		if s, ok := r["valueStr"][idx]; ok {
+			v.String = strings.TrimSpace(s.OctetString)
+		}
+
+		if i, ok := r["valueInt"][idx]; ok && i.Vtype == "Integer" {
+			// Negative scale is divisor, positive scale is multiplier
+			div := 1
+			val := i.Integer
+			if s, ok := r["scale"][idx]; ok {
+				switch {
+				case s.Integer < 0:
+					div = int(-s.Integer)
+				case s.Integer > 0:
+					val = val * s.Integer
+				}
+			}
+			sv := signedSensorVal(val, v.Unit, div)
+			v.Value = sv.Value
+			v.Divisor = sv.Divisor
+		}
+
+		out["Unit"][dName][strings.TrimSpace(n.OctetString)] = v
+	}
+
+	return out, nil
+}
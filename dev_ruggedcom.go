@@ -41,6 +41,11 @@ func (sd *deviceRuggedcom) cliPrepare() (*CliParams, error) {
 	if sd.cliSession.params.DisconnectCmds == nil {
 		params.DisconnectCmds = []string{"logout"}
 	}
+	if sd.cliSession.params.Interact == nil {
+		params.Interact = []CliInteract{
+			{Re: `(?i)press any key[^\r\n]*$`, Send: " ", NoLineEnd: true},
+		}
+	}
 
 	return params, nil
 }
@@ -216,7 +221,15 @@ func (sd *deviceRuggedcom) startCli(p *CliParams) error {
 // Execute cli commands. Returns all sent, received data as string slice
 // c - cli commands, f - check for command errors
 func (sd *deviceRuggedcom) cliCmds(c []string, f bool) ([]string, error) {
-	var output []string
+	res, err := sd.cliCmdsRes(c, f)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+// c - cli commands, f - check for command errors,
+// extra - additional interactive prompt rules (checked before session rules)
+func (sd *deviceRuggedcom) cliCmdsRes(c []string, f bool, extra ...CliInteract) ([]*CliCmdResult, error) {
+	var output []*CliCmdResult
 	e := sd.cliSession.client
 	if e == nil {
 		return output, fmt.Errorf("active cli session not found")
@@ -225,10 +238,15 @@ func (sd *deviceRuggedcom) cliCmds(c []string, f bool) ([]string, error) {
 	pRe := regexp.MustCompile(sd.cliSession.params.PromptRe)
 	eRe := regexp.MustCompile(sd.cliSession.params.ErrRe)
 
+	sd.cliSession.lastUsed = time.Now()
+
 	cnt := len(c)
-	for _, cmd := range c {
+	for n, cmd := range c {
 		cnt--
-		output = append(output, cmd)
+		r := &CliCmdResult{Cmd: cmd}
+		output = append(output, r)
+		start := time.Now()
+
 		pieces := strings.SplitAfter(cmd, " ")
 		last := len(pieces) - 1
 		for i, v := range pieces {
@@ -237,22 +255,28 @@ func (sd *deviceRuggedcom) cliCmds(c []string, f bool) ([]string, error) {
 				pcmd = pcmd + "\r\n"
 			}
 			err := e.Send(pcmd)
+			// Reconnect broken persistent session before first command
+			if err != nil && n == 0 && i == 0 && sd.cliReconnect() == nil {
+				e = sd.cliSession.client
+				err = e.Send(pcmd)
+			}
 			if err != nil {
 				return output, fmt.Errorf("send(%q) failed: %v", cmd, err)
 			}
 		}
 
 		// Dont expect specific prompt after last cmd
-		if cnt == 0 {
+		// Persistent session needs prompt to be consumed
+		if cnt == 0 && !sd.cliSession.persistent {
 			pRe = regexp.MustCompile(`(?m).*$`)
 		}
-		out, _, err := e.Expect(pRe, -1)
+		out, m, err := sd.cliExpect(pRe, extra...)
 		out = strings.TrimPrefix(out, cmd+"\r\n")
-		output = append(output, out)
+		r.setOutput(out, m, cnt > 0 || sd.cliSession.persistent, start)
 
 		// Check for errors if requested
 		if f {
-			if eRe.Match([]byte(out)) {
+			if r.ErrMatch = eRe.FindString(out); r.ErrMatch != "" {
 				return output, fmt.Errorf("cli command exec error: %s", out)
 			}
 		}
@@ -265,8 +289,29 @@ func (sd *deviceRuggedcom) cliCmds(c []string, f bool) ([]string, error) {
 	return output, nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceRuggedcom) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceRuggedcom) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceRuggedcom) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -276,12 +321,17 @@ func (sd *deviceRuggedcom) RunCmds(c []string, o *CliCmdOpts) ([]string, error)
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -300,31 +350,82 @@ func (sd *deviceRuggedcom) RunCmds(c []string, o *CliCmdOpts) ([]string, error)
 
 // Initiate tftp backup of device config
 func (sd *deviceRuggedcom) DoBackup() error {
-	if sd.backupParams == nil {
-		return fmt.Errorf("device backup parameters are not defined")
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
 	}
+	targetFile, t := sd.backupTarget(".csv")
 
-	// Get backup parameters
-	host := sd.backupParams.TargetIp
-	if host == "" {
-		return fmt.Errorf("target ip is not defined")
+	if err := sd.backupRecvDefPort("69"); err != nil {
+		return sd.backupDone(host, targetFile, t, err)
 	}
 
-	t := time.Now()
-	loc, _ := time.LoadLocation(sd.timeZone)
-	t = t.In(loc)
+	// Start embedded receiver if requested
+	rcv, err := sd.backupRecvStart("tftp")
+	if err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
 
-	targetFile := sd.backupParams.BasePath + "/" + sd.backupParams.DevIdent + "_" + t.Format(time_iso8601_sec) + ".csv"
+	cmds := []string{"tftp " + host + " put config.csv " + targetFile}
 
-	cmds := []string{
-		"tftp " + host + " put config.csv " + targetFile,
-		"logout",
+	res, err := sd.RunCmds(sd.cliExit(cmds, "logout"), &CliCmdOpts{ChkErr: true})
+	if err != nil {
+		if rcv != nil {
+			rcv.stop()
+		}
+		return sd.backupDone(host, targetFile, t, fmt.Errorf("cli error: %v, output: %s", err, res))
+	}
+
+	if rcv != nil {
+		err = sd.backupRecvFinish(rcv, targetFile)
 	}
 
-	res, err := sd.RunCmds(cmds, &CliCmdOpts{ChkErr: true})
+	return sd.backupDone(host, targetFile, t, err)
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceRuggedcom) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
+}
+
+// Restore device config (config.csv) from tftp server and verify it
+func (sd *deviceRuggedcom) DoRestore(f *BackupFile) error {
+	if err := sd.restoreCheck(f); err != nil {
+		return err
+	}
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.backupRecvDefPort("69"); err != nil {
+		return err
+	}
+
+	// Start embedded server if requested
+	srv, err := sd.backupServeStart("tftp", f)
+	if err != nil {
+		return err
+	}
+
+	cmds := []string{"tftp " + host + " get " + f.Name + " config.csv"}
+	res, err := sd.RunCmds(sd.cliExit(cmds, "logout"), &CliCmdOpts{ChkErr: true})
+	if srv != nil {
+		srv.stop()
+	}
 	if err != nil {
 		return fmt.Errorf("cli error: %v, output: %s", err, res)
 	}
 
-	return nil
+	cfg, err := sd.RuningCfg()
+	if err != nil {
+		return fmt.Errorf("restore verification failed: %v", err)
+	}
+
+	return sd.restoreVerify(cfg, f)
+}
+
+// Get running config (config.csv)
+func (sd *deviceRuggedcom) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, []string{"type config.csv"}, "logout")
 }
@@ -1,15 +1,57 @@
 package godevman
 
-import "strings"
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/aretaja/snmphelper"
+	"github.com/kr/pretty"
+)
 
 // Adds Stulz specific SNMP functionality to snmpCommon type
 type deviceStulz struct {
 	snmpCommon
 }
 
+// Stulz unit setpoint limits (valid values are min <= x <= max)
+var stulzSetpointLimits = map[string][2]float64{
+	"Temp": {15, 35},
+	"Hum":  {20, 80},
+}
+
+// Stulz unit value oids
+// WIB1000 values are table columns indexed by unit index.
+// .29462.10 controller values are scalars of single unit (unit "1").
+var stulzOids = map[string]map[string]string{
+	"wib": {
+		"onOff":         ".1.3.6.1.4.1.39983.1.1.4.1.1.1",
+		"supplyAirTemp": ".1.3.6.1.4.1.39983.1.1.4.1.1.3",
+		"returnAirTemp": ".1.3.6.1.4.1.39983.1.1.4.1.1.4",
+		"returnAirHum":  ".1.3.6.1.4.1.39983.1.1.4.1.1.5",
+		"tempSetpoint":  ".1.3.6.1.4.1.39983.1.1.4.1.1.6",
+		"humSetpoint":   ".1.3.6.1.4.1.39983.1.1.4.1.1.7",
+		"fanSpeed":      ".1.3.6.1.4.1.39983.1.1.4.1.1.8",
+		"compressor":    ".1.3.6.1.4.1.39983.1.1.4.1.1.9",
+		"alarmDescr":    ".1.3.6.1.4.1.39983.1.1.5.1.1.2",
+		"alarmState":    ".1.3.6.1.4.1.39983.1.1.5.1.1.3",
+	},
+	"ctrl": {
+		"onOff":         ".1.3.6.1.4.1.29462.10.1.1.1.65580.0",
+		"supplyAirTemp": ".1.3.6.1.4.1.29462.10.1.1.1.65550.0",
+		"returnAirTemp": ".1.3.6.1.4.1.29462.10.1.1.1.65551.0",
+		"returnAirHum":  ".1.3.6.1.4.1.29462.10.1.1.1.65552.0",
+		"tempSetpoint":  ".1.3.6.1.4.1.29462.10.1.1.1.65560.0",
+		"humSetpoint":   ".1.3.6.1.4.1.29462.10.1.1.1.65561.0",
+		"fanSpeed":      ".1.3.6.1.4.1.29462.10.1.1.1.65570.0",
+		"compressor":    ".1.3.6.1.4.1.29462.10.1.1.1.65575.0",
+		"commonAlarm":   ".1.3.6.1.4.1.29462.10.1.1.1.65590.0",
+	},
+}
+
 // Get running software version
 func (sd *deviceStulz) SwVersion() (string, error) {
-	if strings.HasSuffix(sd.sysObjectId, ".29462.10") {
+	if sd.isController() {
 		oid := ".1.3.6.1.4.1.29462.10.1.1.1.65540.0"
 		r, err := sd.getone(oid)
 		return r[oid].OctetString, err
@@ -22,3 +64,233 @@ func (sd *deviceStulz) SwVersion() (string, error) {
 		return r.Descr.Value, err
 	}
 }
+
+// Returns true if device is .29462.10 controller
+func (sd *deviceStulz) isController() bool {
+	return strings.HasSuffix(sd.sysObjectId, ".29462.10")
+}
+
+// Get unit info from .iso.org.dod.internet.private.enterprises.stulz tree
+// Output groups are unit indexes ("1" on .29462.10 controller).
+// Valid targets values: "All", "Air", "Setpoint", "Fan", "Compressor", "State", "Alarm"
+func (sd *deviceStulz) Sensors(targets []string) (map[string]map[string]map[string]SensorVal, error) {
+	out := make(map[string]map[string]map[string]SensorVal)
+
+	t, err := sensorTargets(targets, "Air", "Setpoint", "Fan", "Compressor", "State", "Alarm")
+	if err != nil || len(t) == 0 {
+		return out, err
+	}
+
+	names := map[string][]string{
+		"Air":        {"supplyAirTemp", "returnAirTemp", "returnAirHum"},
+		"Setpoint":   {"tempSetpoint", "humSetpoint"},
+		"Fan":        {"fanSpeed"},
+		"Compressor": {"compressor"},
+		"State":      {"onOff"},
+	}
+
+	// Collect values as unit index -> value name -> snmp result
+	res := make(map[string]map[string]int64)
+	addRes := func(u, n string, v int64) {
+		if res[u] == nil {
+			res[u] = make(map[string]int64)
+		}
+		res[u][n] = v
+	}
+
+	if sd.isController() {
+		oids := stulzOids["ctrl"]
+		var rOids []string
+		var rNames []string
+		for g, ns := range names {
+			if !t[g] {
+				continue
+			}
+			for _, n := range ns {
+				rOids = append(rOids, oids[n])
+				rNames = append(rNames, n)
+			}
+		}
+		if t["Alarm"] {
+			rOids = append(rOids, oids["commonAlarm"])
+			rNames = append(rNames, "commonAlarm")
+		}
+
+		if len(rOids) > 0 {
+			r, err := sd.snmpSession.Get(rOids)
+			if err != nil {
+				return out, err
+			}
+
+			for i, o := range rOids {
+				if d, ok := r[o]; ok && d.Vtype == "Integer" {
+					addRes("1", rNames[i], d.Integer)
+				}
+			}
+		}
+	} else {
+		oids := stulzOids["wib"]
+		for g, ns := range names {
+			if !t[g] {
+				continue
+			}
+			for _, n := range ns {
+				r, err := sd.snmpSession.Walk(oids[n], true, true)
+				if err != nil && sd.handleErr(err) {
+					return out, err
+				}
+
+				for u, d := range r {
+					if d.Vtype == "Integer" {
+						addRes(u, n, d.Integer)
+					}
+				}
+			}
+		}
+
+		if t["Alarm"] {
+			descr, err := sd.snmpSession.Walk(oids["alarmDescr"], true, true)
+			if err != nil && sd.handleErr(err) {
+				return out, err
+			}
+
+			state, err := sd.snmpSession.Walk(oids["alarmState"], true, true)
+			if err != nil && sd.handleErr(err) {
+				return out, err
+			}
+
+			// alarm table index is <unit index>.<alarm index>
+			for idx, d := range descr {
+				parts := strings.SplitN(idx, ".", 2)
+				s, ok := state[idx]
+				if !ok || len(parts) != 2 {
+					continue
+				}
+
+				// 1 - active, 0 - inactive
+				setSensorVal(out, parts[0], "Alarm", strings.TrimSpace(d.OctetString), SensorVal{Bool: s.Integer == 1, IsSet: true})
+			}
+		}
+	}
+
+	for u, vals := range res {
+		for n, v := range vals {
+			switch n {
+			case "supplyAirTemp":
+				setSensorVal(out, u, "Air", "SupplyTemp", signedSensorVal(v, "°C", 10))
+			case "returnAirTemp":
+				setSensorVal(out, u, "Air", "ReturnTemp", signedSensorVal(v, "°C", 10))
+			case "returnAirHum":
+				setSensorVal(out, u, "Air", "ReturnHum", signedSensorVal(v, "%", 10))
+			case "tempSetpoint":
+				setSensorVal(out, u, "Setpoint", "Temp", signedSensorVal(v, "°C", 10))
+			case "humSetpoint":
+				setSensorVal(out, u, "Setpoint", "Hum", signedSensorVal(v, "%", 10))
+			case "fanSpeed":
+				setSensorVal(out, u, "Fan", "Speed", signedSensorVal(v, "%", 1))
+			case "compressor":
+				setSensorVal(out, u, "Compressor", "Running", SensorVal{Bool: v == 1, IsSet: true})
+			case "onOff":
+				setSensorVal(out, u, "State", "On", SensorVal{Bool: v == 1, IsSet: true})
+			case "commonAlarm":
+				setSensorVal(out, u, "Alarm", "Common", SensorVal{Bool: v != 0, IsSet: true})
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// Set unit setpoint
+// unit - unit index ("1" on .29462.10 controller), name - setpoint name ("Temp"|"Hum"),
+// value - new setpoint value in °C or %
+func (sd *deviceStulz) SetClimateSetpoint(unit, name string, value float64) error {
+	lim, ok := stulzSetpointLimits[name]
+	if !ok {
+		return fmt.Errorf("setpoint %s is not valid", name)
+	}
+
+	if value < lim[0] || value > lim[1] {
+		return fmt.Errorf("setpoint %s value %.1f is out of allowed range %.1f-%.1f", name, value, lim[0], lim[1])
+	}
+
+	oid, err := sd.unitOid(unit, strings.ToLower(name)+"Setpoint")
+	if err != nil {
+		return err
+	}
+
+	pdus := []snmphelper.SetPDU{
+		{
+			Oid:   oid,
+			Vtype: "Integer",
+			Value: int(math.Round(value * 10)),
+		},
+	}
+
+	r, err := sd.snmpSession.Set(pdus)
+	if err != nil {
+		return err
+	}
+
+	// DEBUG
+	if sd.debug > 0 {
+		fmt.Printf("SetClimateSetpoint result: %# v\n", pretty.Formatter(r))
+	}
+
+	return nil
+}
+
+// Switch unit on or off
+// unit - unit index ("1" on .29462.10 controller)
+func (sd *deviceStulz) SetClimateUnitState(unit string, on bool) error {
+	oid, err := sd.unitOid(unit, "onOff")
+	if err != nil {
+		return err
+	}
+
+	v := 0
+	if on {
+		v = 1
+	}
+
+	pdus := []snmphelper.SetPDU{
+		{
+			Oid:   oid,
+			Vtype: "Integer",
+			Value: v,
+		},
+	}
+
+	r, err := sd.snmpSession.Set(pdus)
+	if err != nil {
+		return err
+	}
+
+	// DEBUG
+	if sd.debug > 0 {
+		fmt.Printf("SetClimateUnitState result: %# v\n", pretty.Formatter(r))
+	}
+
+	return nil
+}
+
+// Returns oid of unit value. Checks if unit exists.
+func (sd *deviceStulz) unitOid(unit, name string) (string, error) {
+	if sd.isController() {
+		if unit != "1" {
+			return "", fmt.Errorf("unit %s not found", unit)
+		}
+		return stulzOids["ctrl"][name], nil
+	}
+
+	oid := stulzOids["wib"][name] + "." + unit
+	r, err := sd.getone(oid)
+	if err != nil {
+		return "", fmt.Errorf("unit %s not found: %v", unit, err)
+	}
+	if r[oid].Vtype != "Integer" {
+		return "", fmt.Errorf("unit %s not found", unit)
+	}
+
+	return oid, nil
+}
@@ -1,10 +1,16 @@
 package godevman
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"math"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Adds Teltonika specific functionality to snmpCommon type
@@ -132,3 +138,522 @@ func (sd *deviceTeltonika) MobSignal() (map[string]MobSignal, error) {
 
 	return ret, nil
 }
+
+// Get info from TELTONIKA-MIB mobile table
+// Returns mobile SIM and data usage info. Active SIM is read from web API if web credentials are set.
+// Msisdn is not provided by TELTONIKA-MIB and is not supported (not set).
+func (sd *deviceTeltonika) MobInfo() (map[string]*MobInfo, error) {
+	out := make(map[string]*MobInfo)
+	oid := ".1.3.6.1.4.1.48690.2.2.1"
+	r, err := sd.getmulti(oid, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reIdxs := regexp.MustCompile(`\.(\d+)\.(\d+)$`)
+
+	for o, d := range r {
+		parts := reIdxs.FindStringSubmatch(o)
+		if parts == nil {
+			continue
+		}
+		col, idx := parts[1], parts[2]
+
+		m, ok := out[idx]
+		if !ok {
+			m = new(MobInfo)
+			out[idx] = m
+		}
+
+		str := ValString{Value: strings.TrimSpace(d.OctetString), IsSet: true}
+		counter := func() SensorVal {
+			v, ok := snmpRawUint(d.Raw)
+			return SensorVal{Unit: "B", Divisor: 1, Value: v, IsSet: ok}
+		}
+
+		switch col {
+		case "8":
+			m.Imsi = str
+		case "9":
+			m.SimState = str
+		case "10":
+			m.PinState = str
+		case "11":
+			m.Roaming.Value = strings.Contains(strings.ToLower(str.Value), "roaming")
+			m.Roaming.IsSet = true
+		case "13":
+			m.Operator = str
+		case "25":
+			m.TxToday = counter()
+		case "26":
+			m.RxToday = counter()
+		case "29":
+			m.TxMonth = counter()
+		case "30":
+			m.RxMonth = counter()
+		case "33":
+			m.Iccid = str
+		}
+	}
+
+	if len(sd.webSession.cred) < 2 {
+		return out, nil
+	}
+
+	err = sd.webCall(func() error {
+		for idx, m := range out {
+			mm, err := sd.mobModem(idx)
+			if err != nil {
+				return err
+			}
+			if mm.ActiveSim > 0 {
+				m.ActiveSim.Value = strconv.Itoa(mm.ActiveSim)
+				m.ActiveSim.IsSet = true
+			}
+		}
+		return nil
+	})
+
+	return out, err
+}
+
+// Make http Get request and return byte slice of body.
+// Argument string should contain remainder after base API URL.
+func (sd *deviceTeltonika) WebApiGet(params string) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
+	client := sd.webSession.client
+	if sd.webSession.client == nil {
+		// setup client
+		c, err := sd.webClient(nil)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	}
+
+	res, err := client.Get("https://" + sd.ip + "/api/" + params)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+
+	if res.StatusCode > 299 {
+		return body, fmt.Errorf("response failed with status code: %d", res.StatusCode)
+	}
+
+	return body, nil
+}
+
+// Make http POST request and return byte slice of body.
+// Argument string should contain remainder after base API URL.
+func (sd *deviceTeltonika) WebApiPost(target string, jsonData []byte) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
+	client := sd.webSession.client
+	if sd.webSession.client == nil {
+		// setup client
+		c, err := sd.webClient(nil)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	}
+
+	res, err := client.Post("https://"+sd.ip+"/api/"+target, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+
+	if res.StatusCode > 299 {
+		return body, fmt.Errorf("response failed with status code: %d", res.StatusCode)
+	}
+
+	return body, nil
+}
+
+// Make http PUT request and return byte slice of body.
+// Argument string should contain remainder after base API URL.
+func (sd *deviceTeltonika) WebApiPut(target string, jsonData []byte) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
+	client := sd.webSession.client
+	if sd.webSession.client == nil {
+		// setup client
+		c, err := sd.webClient(nil)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	}
+
+	req, err := http.NewRequest(http.MethodPut, "https://"+sd.ip+"/api/"+target, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+
+	if res.StatusCode > 299 {
+		return body, fmt.Errorf("response failed with status code: %d", res.StatusCode)
+	}
+
+	return body, nil
+}
+
+// Teltonika web API response
+type teltonikaApiRes struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Errors  []struct {
+		Code   int    `json:"code"`
+		Error  string `json:"error"`
+		Source string `json:"source"`
+	} `json:"errors"`
+}
+
+// Parse web API response. Returns data part of response
+func (sd *deviceTeltonika) apiRes(body []byte) (json.RawMessage, error) {
+	var res teltonikaApiRes
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("unmarshal api response failed: %v", err)
+	}
+
+	if !res.Success {
+		msg := "api request failed"
+		for _, e := range res.Errors {
+			msg += " - " + e.Error
+		}
+		return nil, fmt.Errorf(msg)
+	}
+
+	return res.Data, nil
+}
+
+// Login via web API and stores web session in deviceTeltonika.webSession.client.
+// Use this before use of methods which are accessing restricted device web API.
+func (sd *deviceTeltonika) WebAuth(userPass []string) error {
+	if reuse, err := sd.webSession.lease(); err != nil || reuse {
+		return err
+	}
+	defer sd.webSession.leased()
+
+	if len(userPass) < 2 {
+		return fmt.Errorf("web credentials are not defined")
+	}
+
+	jsonData, err := json.Marshal(map[string]string{"username": userPass[0], "password": userPass[1]})
+	if err != nil {
+		return err
+	}
+
+	body, err := sd.WebApiPost("login", jsonData)
+	if err != nil {
+		return err
+	}
+
+	data, err := sd.apiRes(body)
+	if err != nil {
+		return err
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(data, &login); err != nil || login.Token == "" {
+		return fmt.Errorf("authentication failed")
+	}
+
+	client, err := sd.webClient(map[string][]string{"Authorization": {"Bearer " + login.Token}})
+	if err != nil {
+		return err
+	}
+
+	sd.webSession.client = client
+
+	return nil
+}
+
+// Logout via web API and delete web session from deviceTeltonika.webSession.client.
+// Use this after use of methods which are accessing restricted device web API.
+func (sd *deviceTeltonika) WebLogout() error {
+	if sd.webSession.unlease() {
+		return nil
+	}
+	defer sd.webSession.unleased()
+
+	if sd.webSession.client == nil {
+		return nil
+	}
+
+	body, err := sd.WebApiPost("logout", nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := sd.apiRes(body); err != nil {
+		return err
+	}
+
+	sd.webSession.client = nil
+
+	return nil
+}
+
+// Generate configuration backup via web API and download it.
+// File is stored via BackupParams.Store and written to BackupParams.LocalDir directory if it's set.
+func (sd *deviceTeltonika) DoBackup() error {
+	if sd.backupParams == nil {
+		return fmt.Errorf("device backup parameters are not defined")
+	}
+	targetFile, t := sd.backupTarget(".tar.gz")
+
+	if err := sd.WebAuth(sd.webSession.cred); err != nil {
+		return sd.backupDone("", targetFile, t, fmt.Errorf("error: WebAuth - %s", err))
+	}
+
+	body, err := sd.WebApiPost("backup/actions/generate", []byte(`{"data":{"encrypt":"0"}}`))
+	if err == nil {
+		_, err = sd.apiRes(body)
+	}
+
+	var data []byte
+	if err == nil {
+		data, err = sd.WebApiGet("backup/actions/download")
+	}
+
+	if err2 := sd.WebLogout(); err2 != nil && err == nil {
+		err = fmt.Errorf("errors: WebLogout - %s", err2)
+	}
+	if err != nil {
+		return sd.backupDone("", targetFile, t, fmt.Errorf("backup via web api failed: %v", err))
+	}
+
+	f := &BackupFile{
+		Proto: "https",
+		Src:   sd.ip,
+		Time:  time.Now(),
+		Data:  data,
+	}
+
+	return sd.backupDone("", targetFile, t, sd.backupSave(f, targetFile))
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceTeltonika) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
+}
+
+// Run function in authenticated web API session
+func (sd *deviceTeltonika) webCall(fn func() error) error {
+	if err := sd.WebAuth(sd.webSession.cred); err != nil {
+		return fmt.Errorf("error: WebAuth - %s", err)
+	}
+
+	err := fn()
+
+	if err2 := sd.WebLogout(); err2 != nil && err == nil {
+		err = fmt.Errorf("errors: WebLogout - %s", err2)
+	}
+
+	return err
+}
+
+// Make web API request and parse response. Returns data part of response
+func (sd *deviceTeltonika) apiCall(method, target string, data interface{}) (json.RawMessage, error) {
+	var jsonData []byte
+	if data != nil {
+		d, err := json.Marshal(map[string]interface{}{"data": data})
+		if err != nil {
+			return nil, err
+		}
+		jsonData = d
+	}
+
+	var body []byte
+	var err error
+	switch method {
+	case http.MethodGet:
+		body, err = sd.WebApiGet(target)
+	case http.MethodPost:
+		body, err = sd.WebApiPost(target, jsonData)
+	case http.MethodPut:
+		body, err = sd.WebApiPut(target, jsonData)
+	default:
+		return nil, fmt.Errorf("not supported request method: %s", method)
+	}
+	if err != nil {
+		// Error response may contain error description
+		if _, err2 := sd.apiRes(body); err2 != nil && len(body) > 0 {
+			return nil, fmt.Errorf("%s %s failed: %v (%v)", method, target, err, err2)
+		}
+		return nil, fmt.Errorf("%s %s failed: %v", method, target, err)
+	}
+
+	return sd.apiRes(body)
+}
+
+// Teltonika modem status from web API
+type teltonikaModem struct {
+	Id        string `json:"id"`
+	ActiveSim int    `json:"active_sim"`
+}
+
+// Get modem status via web API. id - modem index (MobSignal key)
+func (sd *deviceTeltonika) mobModem(id string) (*teltonikaModem, error) {
+	data, err := sd.apiCall(http.MethodGet, "modems/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var modems []*teltonikaModem
+	if err := json.Unmarshal(data, &modems); err != nil {
+		return nil, fmt.Errorf("unmarshal modem status failed: %v", err)
+	}
+
+	// Modems are listed in order of mobile SNMP table
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 1 || i > len(modems) {
+		return nil, fmt.Errorf("modem %s not found", id)
+	}
+
+	return modems[i-1], nil
+}
+
+// Set via web API
+// Switch active SIM slot (fe. "1")
+func (sd *deviceTeltonika) SwitchMobSim(id, slot string) error {
+	if _, err := strconv.Atoi(slot); err != nil {
+		return fmt.Errorf("not valid SIM slot: %s", slot)
+	}
+
+	return sd.webCall(func() error {
+		m, err := sd.mobModem(id)
+		if err != nil {
+			return err
+		}
+		if strconv.Itoa(m.ActiveSim) == slot {
+			return nil
+		}
+
+		_, err = sd.apiCall(http.MethodPost, "modems/"+m.Id+"/actions/change_sim", map[string]string{"sim": slot})
+		return err
+	})
+}
+
+// Set via web API
+// Change APN settings of SIM mobile interface and SIM PIN
+func (sd *deviceTeltonika) SetMobSim(id string, s *MobSimSet) error {
+	if err := mobSimCheck(s); err != nil {
+		return err
+	}
+
+	iface := make(map[string]string)
+	if s.Apn != nil {
+		iface["apn"] = *s.Apn
+	}
+	if s.ApnUser != nil {
+		iface["username"] = *s.ApnUser
+	}
+	if s.ApnPass != nil {
+		iface["password"] = *s.ApnPass
+	}
+	if s.ApnAuth != nil {
+		iface["auth_mode"] = *s.ApnAuth
+	}
+
+	return sd.webCall(func() error {
+		m, err := sd.mobModem(id)
+		if err != nil {
+			return err
+		}
+
+		slot := s.Slot
+		if slot == "" {
+			slot = strconv.Itoa(m.ActiveSim)
+		}
+
+		if len(iface) > 0 {
+			// Default mobile interface of SIM (fe. mob1s1a1)
+			name := "mob" + id + "s" + slot + "a1"
+			if _, err := sd.apiCall(http.MethodPut, "interfaces/config/"+name, iface); err != nil {
+				return err
+			}
+		}
+
+		if s.Pin != nil {
+			data, err := sd.apiCall(http.MethodGet, "sim_cards/config", nil)
+			if err != nil {
+				return err
+			}
+
+			var sims []struct {
+				Id       string `json:"id"`
+				Modem    string `json:"modem"`
+				Position string `json:"position"`
+			}
+			if err := json.Unmarshal(data, &sims); err != nil {
+				return fmt.Errorf("unmarshal SIM config failed: %v", err)
+			}
+
+			simId := ""
+			for _, c := range sims {
+				if c.Modem == m.Id && c.Position == slot {
+					simId = c.Id
+				}
+			}
+			if simId == "" {
+				return fmt.Errorf("SIM %s of modem %s not found", slot, id)
+			}
+
+			if _, err := sd.apiCall(http.MethodPut, "sim_cards/config/"+simId, map[string]string{"pincode": *s.Pin}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Set via web API
+// Reset mobile modem
+func (sd *deviceTeltonika) ResetMob(id, kind string) error {
+	if err := mobResetCheck(kind); err != nil {
+		return err
+	}
+
+	return sd.webCall(func() error {
+		m, err := sd.mobModem(id)
+		if err != nil {
+			return err
+		}
+
+		target := "modems/" + m.Id + "/actions/"
+		switch kind {
+		case MobResetSession:
+			target = "interfaces/mob" + id + "s" + strconv.Itoa(m.ActiveSim) + "a1/actions/restart"
+		case MobResetReconnect:
+			target += "reconnect"
+		case MobResetPower:
+			target += "reboot"
+		}
+
+		_, err = sd.apiCall(http.MethodPost, target, nil)
+		return err
+	})
+}
@@ -8,8 +8,10 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aretaja/snmphelper"
 	"github.com/patrickmn/go-cache"
@@ -43,6 +45,12 @@ type UbiOltInterfaceSfp struct {
 		Part    *string     `json:"part"`
 		Vendor  *string     `json:"vendor"`
 		Present *bool       `json:"present"`
+		// Diagnostics of SFP modules supporting DDM
+		TxPower     *float64 `json:"txPower"`
+		RxPower     *float64 `json:"rxPower"`
+		LaserBias   *float64 `json:"laserBias"`
+		Temperature *float64 `json:"temperature"`
+		Voltage     *float64 `json:"voltage"`
 	}
 }
 
@@ -144,17 +152,23 @@ type UbiOltStatistics []struct {
 // Ubiquiti specific VLANs type used by device web API
 type UbiOltVlans struct {
 	Trunks []interface{} `json:"trunks"`
-	Vlans  []struct {
-		Name          string `json:"name"`
-		Type          string `json:"type"`
-		Participation []struct {
-			Interface struct {
-				ID string `json:"id"`
-			} `json:"interface"`
-			Mode string `json:"mode"`
-		} `json:"participation"`
-		ID int `json:"id"`
-	} `json:"vlans"`
+	Vlans  []UbiOltVlan  `json:"vlans"`
+}
+
+// Ubiquiti specific VLAN type used by device web API
+type UbiOltVlan struct {
+	Name          string           `json:"name"`
+	Type          string           `json:"type"`
+	Participation []UbiOltVlanPort `json:"participation"`
+	ID            int              `json:"id"`
+}
+
+// Ubiquiti specific VLAN member port type used by device web API
+type UbiOltVlanPort struct {
+	Interface struct {
+		ID string `json:"id"`
+	} `json:"interface"`
+	Mode string `json:"mode"`
 }
 
 // Ubiquiti specific ONU info type used by device web API
@@ -250,11 +264,7 @@ type UbiOnuSettings struct {
 		Speed *string `json:"speed"`
 	} `json:"ports"`
 	BridgeMode struct {
-		Ports []struct {
-			Port         *string `json:"port"`
-			NativeVLAN   *int    `json:"nativeVLAN"`
-			IncludeVLANs []int   `json:"includeVLANs"`
-		} `json:"ports"`
+		Ports []UbiOnuBridgePort `json:"ports"`
 	} `json:"bridgeMode"`
 	RouterMode struct {
 		RouterAdvertisement struct {
@@ -298,6 +308,24 @@ type UbiOnuSettings struct {
 
 type UbiOnusSettings []UbiOnuSettings
 
+// Ubiquiti specific ONU bridge mode port settings type used by device web API
+type UbiOnuBridgePort struct {
+	Port         *string `json:"port"`
+	NativeVLAN   *int    `json:"nativeVLAN"`
+	IncludeVLANs []int   `json:"includeVLANs"`
+}
+
+type UbiOltAlarm struct {
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	Severity     string `json:"severity"`
+	Message      string `json:"message"`
+	Timestamp    int64  `json:"timestamp"`
+	Acknowledged bool   `json:"acknowledged"`
+}
+
+type UbiOltAlarms []UbiOltAlarm
+
 // Get running software version
 func (sd *deviceUbiquiti) SwVersion() (string, error) {
 	oid := ".1.3.6.1.4.1.41112.1.5.1.3.0"
@@ -322,6 +350,8 @@ func (sd *deviceUbiquiti) IfNumber() (int64, error) {
 // Make http GET request and return byte slice of body.
 // Argument string should contain request parameters.
 func (sd *deviceUbiquiti) WebApiGet(params string) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
 	client := sd.webSession.client
 	if sd.webSession.client == nil {
 		// setup client
@@ -351,6 +381,8 @@ func (sd *deviceUbiquiti) WebApiGet(params string) ([]byte, error) {
 // Make http POST request and return byte slice of body.
 // Argument string should contain request parameters.
 func (sd *deviceUbiquiti) WebApiPost(target string, jsonData []byte) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
 	client := sd.webSession.client
 	if sd.webSession.client == nil {
 		// setup client
@@ -381,6 +413,8 @@ func (sd *deviceUbiquiti) WebApiPost(target string, jsonData []byte) ([]byte, er
 // Make http PUT request and return byte slice of body.
 // Argument string should contain request parameters.
 func (sd *deviceUbiquiti) WebApiPut(target string, jsonData []byte) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
 	client := sd.webSession.client
 	if sd.webSession.client == nil {
 		// setup client
@@ -413,9 +447,50 @@ func (sd *deviceUbiquiti) WebApiPut(target string, jsonData []byte) ([]byte, err
 	return body, nil
 }
 
+// Make http DELETE request and return byte slice of body.
+// Argument string should contain request parameters.
+func (sd *deviceUbiquiti) WebApiDelete(target string) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
+	client := sd.webSession.client
+	if sd.webSession.client == nil {
+		// setup client
+		c, err := sd.webClient(nil)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	}
+
+	baseUrl := "https://" + sd.ip + "/api/v1.0/"
+	req, err := http.NewRequest(http.MethodDelete, baseUrl+target, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+
+	if res.StatusCode > 299 {
+		return body, fmt.Errorf("response failed with status code: %d", res.StatusCode)
+	}
+
+	return body, nil
+}
+
 // Login via web API and stores web session in deviceUbiquiti.websession.
 // Use this before use of methods which are accessing restricted device web API.
 func (sd *deviceUbiquiti) WebAuth(userPass []string) error {
+	if reuse, err := sd.webSession.lease(); err != nil || reuse {
+		return err
+	}
+	defer sd.webSession.leased()
+
 	// setup client
 	client, err := sd.webClient(nil)
 	if err != nil {
@@ -463,6 +538,11 @@ func (sd *deviceUbiquiti) WebLogout() error {
 		return nil
 	}
 
+	if sd.webSession.unlease() {
+		return nil
+	}
+	defer sd.webSession.unleased()
+
 	res, err := sd.webSession.client.Post("https://"+sd.ip+"/api/v1.0/user/logout", "application/json", nil)
 	if err != nil {
 		return err
@@ -691,6 +771,45 @@ func (sd *deviceUbiquiti) oltOnuSettings() (*UbiOnusSettings, error) {
 	return info, nil
 }
 
+// Get OLT active alarms via web API.
+func (sd *deviceUbiquiti) oltAlarms() (*UbiOltAlarms, error) {
+	// return from cache if allowed and cache is present
+	if sd.useCache {
+		if x, found := sd.cache.Get("oltAlarms"); found {
+			return x.(*UbiOltAlarms), nil
+		}
+	}
+
+	if err := sd.WebAuth(sd.webSession.cred); err != nil {
+		return nil, fmt.Errorf("error: WebAuth - %s", err)
+	}
+
+	body, err := sd.WebApiGet("alarms")
+	if err != nil {
+		return nil, fmt.Errorf("get request from device api failed: %s", err)
+	}
+
+	err = sd.WebLogout()
+	if err != nil {
+		return nil, fmt.Errorf("errors: WebLogout - %s", err)
+	}
+
+	info := new(UbiOltAlarms)
+	err = json.Unmarshal(body, info)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal OLT alarms failed: %s", err)
+	}
+
+	if info == nil {
+		return nil, fmt.Errorf("no OLT alarms")
+	}
+
+	// save to cache
+	sd.cache.Set("oltAlarms", info, cache.DefaultExpiration)
+
+	return info, nil
+}
+
 // Get info from .iso.org.dod.internet.private.enterprises.ubnt.ubntMIB.ubntEdgeMax.ubntSfps.ubntSfpsTable and device web API
 // Valid targets values: "All", "Descr", "Name", "Alias", "Type", "Speed", "Mac", "Admin",
 // "Oper", "InOctets", "InPkts", "InMcast", "InBcast", "InErrors", "OutOctets", "OutPkts",
@@ -901,6 +1020,8 @@ func (sd *deviceUbiquiti) IfInfo(targets []string, idx ...string) (map[string]*I
 						if *id.ID == wDescr && id.Statistics.RxBytes != nil {
 							out[i].InOctets.Value = *id.Statistics.RxBytes
 							out[i].InOctets.IsSet = true
+							out[i].Hc.Value = true
+							out[i].Hc.IsSet = true
 						}
 					}
 					break
@@ -956,6 +1077,8 @@ func (sd *deviceUbiquiti) IfInfo(targets []string, idx ...string) (map[string]*I
 						if *id.ID == wDescr && id.Statistics.TxBytes != nil {
 							out[i].OutOctets.Value = *id.Statistics.TxBytes
 							out[i].OutOctets.IsSet = true
+							out[i].Hc.Value = true
+							out[i].Hc.IsSet = true
 						}
 					}
 					break
@@ -1240,6 +1363,171 @@ func (sd *deviceUbiquiti) D1qVlanInfo() (map[string]*D1qVlanInfo, error) {
 	return out, err
 }
 
+// Get current VLAN settings and info (bypasses cache)
+func (sd *deviceUbiquiti) vlanState() (*UbiOltVlans, map[string]*D1qVlanInfo, error) {
+	sd.cache.Delete("oltVlans")
+
+	cur, err := sd.D1qVlanInfo()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v, err := sd.oltVlans()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return v, cur, nil
+}
+
+// Save VLAN settings via web API
+func (sd *deviceUbiquiti) putVlans(v *UbiOltVlans) error {
+	defer sd.cache.Delete("oltVlans")
+
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return sd.webCall(func() error {
+		if _, err := sd.WebApiPut("vlans", jsonData); err != nil {
+			return fmt.Errorf("errors: WebApiPut - %s", err)
+		}
+		return nil
+	})
+}
+
+// Set via web API
+// Create VLAN
+func (sd *deviceUbiquiti) AddVlan(vid int, name string) error {
+	v, cur, err := sd.vlanState()
+	if err != nil {
+		return err
+	}
+	if err := vlanAddCheck(cur, vid); err != nil {
+		return err
+	}
+
+	nv := UbiOltVlan{Name: name, ID: vid}
+	// New VLAN gets type of existing VLANs
+	if len(v.Vlans) > 0 {
+		nv.Type = v.Vlans[0].Type
+	}
+	v.Vlans = append(v.Vlans, nv)
+
+	return sd.putVlans(v)
+}
+
+// Set via web API
+// Remove VLAN
+func (sd *deviceUbiquiti) DelVlan(vid int) error {
+	v, cur, err := sd.vlanState()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanDelCheck(cur, vid); err != nil {
+		return err
+	}
+
+	var keep []UbiOltVlan
+	for _, vl := range v.Vlans {
+		if vl.ID != vid {
+			keep = append(keep, vl)
+		}
+	}
+	v.Vlans = keep
+
+	return sd.putVlans(v)
+}
+
+// Set via web API
+// Rename VLAN
+func (sd *deviceUbiquiti) RenameVlan(vid int, name string) error {
+	v, cur, err := sd.vlanState()
+	if err != nil {
+		return err
+	}
+	if err := vlanExists(cur, vid); err != nil {
+		return err
+	}
+
+	for i := range v.Vlans {
+		if v.Vlans[i].ID == vid {
+			v.Vlans[i].Name = name
+		}
+	}
+
+	return sd.putVlans(v)
+}
+
+// Set via web API
+// Set VLAN port membership
+// set - map of ifIndexes and their membership modes (tagged|untagged|none)
+func (sd *deviceUbiquiti) SetVlanPorts(vid int, set map[string]string) error {
+	v, cur, err := sd.vlanState()
+	if err != nil {
+		return err
+	}
+	if err := sd.vlanPortsCheck(cur, vid, set, nil); err != nil {
+		return err
+	}
+
+	ifInfo, err := sd.IfInfo([]string{"Descr"})
+	if err != nil {
+		return err
+	}
+
+	idxs := make([]string, 0, len(set))
+	for k := range set {
+		idxs = append(idxs, k)
+	}
+	sort.Strings(idxs)
+
+	dSet := make(map[string]string)
+	for _, idx := range idxs {
+		info, ok := ifInfo[idx]
+		if !ok || !info.Descr.IsSet {
+			return fmt.Errorf("interface with ifindex %s not found", idx)
+		}
+
+		dSet[info.Descr.Value] = set[idx]
+	}
+
+	for i := range v.Vlans {
+		vl := &v.Vlans[i]
+		if vl.ID != vid {
+			continue
+		}
+
+		var ports []UbiOltVlanPort
+		for _, p := range vl.Participation {
+			if _, ok := dSet[p.Interface.ID]; !ok {
+				ports = append(ports, p)
+			}
+		}
+
+		for _, idx := range idxs {
+			m := set[idx]
+			if m == VlanPortNone {
+				continue
+			}
+
+			var p UbiOltVlanPort
+			p.Interface.ID = ifInfo[idx].Descr.Value
+			p.Mode = m
+			ports = append(ports, p)
+		}
+		vl.Participation = ports
+	}
+
+	return sd.putVlans(v)
+}
+
+// Port VLAN id follows untagged VLAN membership on this device
+func (sd *deviceUbiquiti) SetPvid(set map[string]int) error {
+	return fmt.Errorf("port VLAN id is defined by untagged VLAN membership, use SetVlanPorts")
+}
+
 // Get info via web API
 func (sd *deviceUbiquiti) IpInfo(ip ...string) (map[string]*IpInfo, error) {
 	out := make(map[string]*IpInfo)
@@ -1710,6 +1998,14 @@ func (sd *deviceUbiquiti) OnuInfo() (map[string]*OnuInfo, error) {
 
 				o.RxPower = v
 			}
+			if i.LaserBias != nil {
+				o.LaserBias = SensorVal{
+					Unit:    "mA",
+					Divisor: 100,
+					Value:   uint64(math.Abs(*i.LaserBias * 100)),
+					IsSet:   true,
+				}
+			}
 			if i.System.Mem != nil {
 				v := SensorVal{
 					Unit:    "%",
@@ -1840,6 +2136,71 @@ func (sd *deviceUbiquiti) OnuInfo() (map[string]*OnuInfo, error) {
 	return out, err
 }
 
+// Get info from device web API
+// Returns optical info of OLT PON ports. Map keys are PON port ids (fe. "pon1")
+func (sd *deviceUbiquiti) PonOptics() (map[string]*PonOptics, error) {
+	out := make(map[string]*PonOptics)
+
+	info, err := sd.oltIfInfo()
+	if err != nil {
+		return out, err
+	}
+
+	// Returns signed SensorVal for float value
+	fVal := func(v float64, unit string) SensorVal {
+		return signedSensorVal(int64(math.Round(v*100)), unit, 100)
+	}
+
+	for _, i := range *info {
+		if i.Identification.ID == nil || !strings.HasPrefix(*i.Identification.ID, "pon") {
+			continue
+		}
+
+		sfp := i.Pon.Sfp
+		o := new(PonOptics)
+
+		if sfp.Vendor != nil {
+			o.Vendor.Value = *sfp.Vendor
+			o.Vendor.IsSet = true
+		}
+		if sfp.Part != nil {
+			o.Part.Value = *sfp.Part
+			o.Part.IsSet = true
+		}
+		if sfp.Serial != nil {
+			o.Serial.Value = *sfp.Serial
+			o.Serial.IsSet = true
+		}
+		if sfp.Present != nil {
+			o.Present.Value = *sfp.Present
+			o.Present.IsSet = true
+		}
+		if los, ok := sfp.Los.(bool); ok {
+			o.Los.Value = los
+			o.Los.IsSet = true
+		}
+		if sfp.TxPower != nil {
+			o.TxPower = fVal(*sfp.TxPower, "dBm")
+		}
+		if sfp.RxPower != nil {
+			o.RxPower = fVal(*sfp.RxPower, "dBm")
+		}
+		if sfp.LaserBias != nil {
+			o.LaserBias = fVal(*sfp.LaserBias, "mA")
+		}
+		if sfp.Temperature != nil {
+			o.Temperature = fVal(*sfp.Temperature, "°C")
+		}
+		if sfp.Voltage != nil {
+			o.Voltage = fVal(*sfp.Voltage, "V")
+		}
+
+		out[*i.Identification.ID] = o
+	}
+
+	return out, nil
+}
+
 // Prepare CLI session parameters
 func (sd *deviceUbiquiti) cliPrepare() (*CliParams, error) {
 	defParams, err := sd.snmpCommon.cliPrepare()
@@ -1863,8 +2224,29 @@ func (sd *deviceUbiquiti) cliPrepare() (*CliParams, error) {
 	return params, nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceUbiquiti) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceUbiquiti) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceUbiquiti) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -1874,12 +2256,17 @@ func (sd *deviceUbiquiti) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -1895,3 +2282,43 @@ func (sd *deviceUbiquiti) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 
 	return out, nil
 }
+
+// Get active alarms via web API
+func (sd *deviceUbiquiti) Alarms() ([]*AlarmInfo, error) {
+	var out []*AlarmInfo
+
+	info, err := sd.oltAlarms()
+	if err != nil {
+		return out, err
+	}
+
+	severity := map[string]string{
+		"critical": "critical",
+		"error":    "major",
+		"major":    "major",
+		"minor":    "minor",
+		"warning":  "warning",
+	}
+
+	for _, i := range *info {
+		sev, ok := severity[strings.ToLower(i.Severity)]
+		if !ok {
+			sev = "indeterminate"
+		}
+
+		a := &AlarmInfo{
+			Source:   ValString{Value: i.Source, IsSet: true},
+			Severity: ValString{Value: sev, IsSet: true},
+			Text:     ValString{Value: i.Message, IsSet: true},
+			Acked:    ValBool{Value: i.Acknowledged, IsSet: true},
+		}
+
+		if i.Timestamp > 0 {
+			a.setRaised(time.Unix(i.Timestamp, 0))
+		}
+
+		out = append(out, a)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,567 @@
+package godevman
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Run web API requests in authenticated session
+func (sd *deviceUbiquiti) webCall(fn func() error) (err error) {
+	if err := sd.WebAuth(sd.webSession.cred); err != nil {
+		return fmt.Errorf("error: WebAuth - %s", err)
+	}
+
+	defer func() {
+		if err2 := sd.WebLogout(); err2 != nil {
+			if err != nil {
+				err = fmt.Errorf("%w; WebLogout - %s", err, err2)
+			} else {
+				err = fmt.Errorf("error: WebLogout - %s", err2)
+			}
+		}
+	}()
+
+	return fn()
+}
+
+// Get current ONU info and settings (bypasses cache). Map keys are ONU serials
+func (sd *deviceUbiquiti) onuState() (map[string]*UbiOnuInfo, map[string]*UbiOnuSettings, error) {
+	sd.cache.Delete("oltOnus")
+	sd.cache.Delete("oltOnuSettings")
+
+	oInfo, err := sd.oltOnus()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oSettings, err := sd.oltOnuSettings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	onus := make(map[string]*UbiOnuInfo)
+	for i := range *oInfo {
+		if o := &(*oInfo)[i]; o.Serial != nil {
+			onus[*o.Serial] = o
+		}
+	}
+
+	settings := make(map[string]*UbiOnuSettings)
+	for i := range *oSettings {
+		if s := &(*oSettings)[i]; s.Serial != nil {
+			settings[*s.Serial] = s
+		}
+	}
+
+	return onus, settings, nil
+}
+
+// Authorize ONU
+// serial - ONU serial, name - ONU name
+func (sd *deviceUbiquiti) AuthorizeOnu(serial, name string) error {
+	if name == "" {
+		return fmt.Errorf("ONU name is not defined")
+	}
+
+	onus, settings, err := sd.onuState()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := onus[serial]; !ok {
+		return fmt.Errorf("ONU %s not found", serial)
+	}
+	if _, ok := settings[serial]; ok {
+		return fmt.Errorf("ONU %s is already authorized", serial)
+	}
+
+	jsonData, err := json.Marshal([]map[string]interface{}{
+		{"serial": serial, "name": name, "enabled": true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = sd.webCall(func() error {
+		if _, err := sd.WebApiPut("gpon/onus/settings", jsonData); err != nil {
+			return fmt.Errorf("errors: WebApiPut - %s", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Read back
+	_, settings, err = sd.onuState()
+	if err != nil {
+		return fmt.Errorf("read back failed: %v", err)
+	}
+	if _, ok := settings[serial]; !ok {
+		return fmt.Errorf("ONU %s is not authorized after change", serial)
+	}
+
+	return nil
+}
+
+// Remove ONU authorization
+func (sd *deviceUbiquiti) UnauthorizeOnu(serial string) error {
+	_, settings, err := sd.onuState()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := settings[serial]; !ok {
+		return fmt.Errorf("ONU %s is not authorized", serial)
+	}
+
+	err = sd.webCall(func() error {
+		if _, err := sd.WebApiDelete("gpon/onus/settings/" + serial); err != nil {
+			return fmt.Errorf("errors: WebApiDelete - %s", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Read back
+	_, settings, err = sd.onuState()
+	if err != nil {
+		return fmt.Errorf("read back failed: %v", err)
+	}
+	if _, ok := settings[serial]; ok {
+		return fmt.Errorf("ONU %s is still authorized after change", serial)
+	}
+
+	return nil
+}
+
+// Reboot ONU
+func (sd *deviceUbiquiti) RebootOnu(serial string) error {
+	onus, _, err := sd.onuState()
+	if err != nil {
+		return err
+	}
+
+	o, ok := onus[serial]
+	if !ok {
+		return fmt.Errorf("ONU %s not found", serial)
+	}
+	if o.Connected == nil || !*o.Connected {
+		return fmt.Errorf("ONU %s is not connected", serial)
+	}
+
+	return sd.webCall(func() error {
+		if _, err := sd.WebApiPost("gpon/onus/"+serial+"/reboot", nil); err != nil {
+			return fmt.Errorf("errors: WebApiPost - %s", err)
+		}
+		return nil
+	})
+}
+
+// Change ONU settings. Changes are validated against current settings
+// and verified by reading settings back.
+// set - map of ONU serials and their changes
+func (sd *deviceUbiquiti) SetOnus(set map[string]*OnuSet) error {
+	// Settings are modified in place
+	defer sd.cache.Delete("oltOnuSettings")
+
+	_, settings, err := sd.onuState()
+	if err != nil {
+		return err
+	}
+
+	// VLANs defined on OLT are needed for validation
+	var vlans map[int]bool
+	for _, c := range set {
+		if c != nil && (c.WanVlan != nil || c.PortVlans != nil) {
+			v, err := sd.oltVlans()
+			if err != nil {
+				return err
+			}
+			vlans = make(map[int]bool)
+			for _, vl := range v.Vlans {
+				vlans[vl.ID] = true
+			}
+			break
+		}
+	}
+
+	serials := make([]string, 0, len(set))
+	for serial := range set {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+
+	var upd UbiOnusSettings
+	for _, serial := range serials {
+		s, ok := settings[serial]
+		if !ok {
+			return fmt.Errorf("ONU %s is not authorized", serial)
+		}
+
+		changed, err := ubiOnuApply(s, set[serial], vlans)
+		if err != nil {
+			return fmt.Errorf("ONU %s: %v", serial, err)
+		}
+		if changed {
+			upd = append(upd, *s)
+		}
+	}
+
+	if len(upd) == 0 {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(upd)
+	if err != nil {
+		return err
+	}
+
+	err = sd.webCall(func() error {
+		if _, err := sd.WebApiPut("gpon/onus/settings", jsonData); err != nil {
+			return fmt.Errorf("errors: WebApiPut - %s", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Read back
+	_, settings, err = sd.onuState()
+	if err != nil {
+		return fmt.Errorf("read back failed: %v", err)
+	}
+	for _, u := range upd {
+		s, ok := settings[*u.Serial]
+		if !ok {
+			return fmt.Errorf("ONU %s settings not found after change", *u.Serial)
+		}
+		if changed, _ := ubiOnuApply(s, set[*u.Serial], nil); changed {
+			return fmt.Errorf("ONU %s settings are not changed as requested", *u.Serial)
+		}
+	}
+
+	return nil
+}
+
+// Validate and apply ONU settings change. Returns true if settings changed.
+// vlans - VLANs defined on OLT (nil - VLANs are not validated)
+func ubiOnuApply(s *UbiOnuSettings, c *OnuSet, vlans map[int]bool) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+
+	changed := false
+	chkVlan := func(v int) error {
+		if v < 1 || v > 4094 {
+			return fmt.Errorf("VLAN %d is not valid", v)
+		}
+		if vlans != nil && !vlans[v] {
+			return fmt.Errorf("VLAN %d is not defined on OLT", v)
+		}
+		return nil
+	}
+
+	if c.Name != nil {
+		if *c.Name == "" {
+			return false, fmt.Errorf("name can't be empty")
+		}
+		if s.Name == nil || *s.Name != *c.Name {
+			s.Name = c.Name
+			changed = true
+		}
+	}
+
+	if c.Enabled != nil && (s.Enabled == nil || *s.Enabled != *c.Enabled) {
+		s.Enabled = c.Enabled
+		changed = true
+	}
+
+	if c.Mode != nil {
+		if *c.Mode != "router" && *c.Mode != "bridge" {
+			return false, fmt.Errorf("mode %s is not valid", *c.Mode)
+		}
+		if s.Mode == nil || *s.Mode != *c.Mode {
+			s.Mode = c.Mode
+			changed = true
+		}
+	}
+
+	// Bandwidth limits
+	for _, l := range []struct {
+		val     *int
+		enabled **bool
+		limit   **int
+	}{
+		{c.DownLimit, &s.BandwidthLimit.Download.Enabled, &s.BandwidthLimit.Download.Limit},
+		{c.UpLimit, &s.BandwidthLimit.Upload.Enabled, &s.BandwidthLimit.Upload.Limit},
+	} {
+		if l.val == nil {
+			continue
+		}
+		if *l.val < 0 {
+			return false, fmt.Errorf("bandwidth limit %d is not valid", *l.val)
+		}
+
+		en := *l.val > 0
+		if *l.enabled == nil || **l.enabled != en {
+			*l.enabled = &en
+			changed = true
+		}
+		if en && (*l.limit == nil || **l.limit != *l.val) {
+			v := *l.val
+			*l.limit = &v
+			changed = true
+		}
+	}
+
+	if c.WanVlan != nil {
+		if err := chkVlan(*c.WanVlan); err != nil {
+			return false, err
+		}
+		if s.RouterMode.WanVLAN == nil || *s.RouterMode.WanVLAN != *c.WanVlan {
+			s.RouterMode.WanVLAN = c.WanVlan
+			changed = true
+		}
+	}
+
+	for port, pv := range c.PortVlans {
+		if pv == nil {
+			continue
+		}
+
+		found := false
+		for _, p := range s.Ports {
+			if p.ID != nil && *p.ID == port {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, fmt.Errorf("port %s not found", port)
+		}
+
+		for _, v := range append([]int{pv.Native}, pv.Include...) {
+			if err := chkVlan(v); err != nil {
+				return false, fmt.Errorf("port %s: %v", port, err)
+			}
+		}
+
+		idx := -1
+		for i, p := range s.BridgeMode.Ports {
+			if p.Port != nil && *p.Port == port {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			p := port
+			s.BridgeMode.Ports = append(s.BridgeMode.Ports, UbiOnuBridgePort{Port: &p})
+			idx = len(s.BridgeMode.Ports) - 1
+		}
+
+		bp := &s.BridgeMode.Ports[idx]
+		if bp.NativeVLAN == nil || *bp.NativeVLAN != pv.Native || !intsEqual(bp.IncludeVLANs, pv.Include) {
+			n := pv.Native
+			bp.NativeVLAN = &n
+			bp.IncludeVLANs = append([]int{}, pv.Include...)
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+// Compare int slices ignoring order
+func intsEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sa := append([]int{}, a...)
+	sb := append([]int{}, b...)
+	sort.Ints(sa)
+	sort.Ints(sb)
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Upgrade firmware of outdated ONUs.
+// ONUs are upgraded in batches, every PON port upgrades up to BatchSize ONUs at time.
+// Next batches are not started if number of failed upgrades reaches MaxFailures.
+func (sd *deviceUbiquiti) UpgradeOnus(p *OnuUpgradeParams) ([]*OnuUpgradeResult, error) {
+	var out []*OnuUpgradeResult
+
+	if p == nil || p.Version == "" {
+		return out, fmt.Errorf("target firmware version is not defined")
+	}
+
+	batchSize := p.BatchSize
+	if batchSize <= 0 {
+		batchSize = 4
+	}
+	maxFailures := p.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = 1
+	}
+	poll := time.Duration(p.PollInterval) * time.Second
+	if poll <= 0 {
+		poll = 10 * time.Second
+	}
+	timeout := time.Duration(p.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 900 * time.Second
+	}
+
+	onus, settings, err := sd.onuState()
+	if err != nil {
+		return out, err
+	}
+
+	only := make(map[string]bool)
+	for _, s := range p.Serials {
+		only[s] = true
+	}
+
+	// Outdated ONUs per PON port
+	queues := make(map[string][]*OnuUpgradeResult)
+	for serial, o := range onus {
+		if _, ok := settings[serial]; !ok || o.OltPort == nil {
+			continue
+		}
+		if len(only) > 0 && !only[serial] {
+			continue
+		}
+		if o.Connected == nil || !*o.Connected || o.FirmwareVersion == nil || *o.FirmwareVersion == p.Version {
+			continue
+		}
+
+		port := "pon" + strconv.Itoa(*o.OltPort)
+		queues[port] = append(queues[port], &OnuUpgradeResult{
+			Serial:      serial,
+			OltPort:     port,
+			FromVersion: *o.FirmwareVersion,
+		})
+	}
+
+	ports := make([]string, 0, len(queues))
+	for port, q := range queues {
+		sort.Slice(q, func(i, j int) bool { return q[i].Serial < q[j].Serial })
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+
+	failures := 0
+	for {
+		// Next batch from every PON port
+		var batch []*OnuUpgradeResult
+		for _, port := range ports {
+			n := batchSize
+			if n > len(queues[port]) {
+				n = len(queues[port])
+			}
+			batch = append(batch, queues[port][:n]...)
+			queues[port] = queues[port][n:]
+		}
+		if len(batch) == 0 {
+			return out, nil
+		}
+		out = append(out, batch...)
+
+		failures += sd.onuUpgradeBatch(batch, p.Version, poll, timeout)
+		if failures >= maxFailures {
+			return out, fmt.Errorf("upgrade stopped: %d ONU upgrades failed", failures)
+		}
+	}
+}
+
+// Upgrade batch of ONUs and wait until upgrades are finished. Returns number of failed upgrades
+func (sd *deviceUbiquiti) onuUpgradeBatch(batch []*OnuUpgradeResult, version string, poll, timeout time.Duration) int {
+	failures := 0
+	fail := func(r *OnuUpgradeResult, reason string) {
+		r.FailureReason = reason
+		failures++
+	}
+
+	pending := make(map[string]*OnuUpgradeResult)
+	requested := false
+	err := sd.webCall(func() error {
+		for _, r := range batch {
+			if _, err := sd.WebApiPost("gpon/onus/"+r.Serial+"/upgrade", nil); err != nil {
+				fail(r, fmt.Sprintf("upgrade request failed: %v", err))
+				continue
+			}
+			pending[r.Serial] = r
+		}
+		requested = true
+		return nil
+	})
+	if err != nil {
+		// Session setup failed, upgrades were not requested
+		if !requested {
+			for _, r := range batch {
+				if r.FailureReason == "" {
+					fail(r, err.Error())
+				}
+			}
+			return failures
+		}
+		// Only logout failed, requested upgrades are running
+		log.Printf("warning: %v\n", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for len(pending) > 0 {
+		if time.Now().After(deadline) {
+			for _, r := range pending {
+				fail(r, "upgrade timeout")
+			}
+			break
+		}
+		time.Sleep(poll)
+
+		onus, _, err := sd.onuState()
+		if err != nil {
+			// OLT may be busy, try again
+			continue
+		}
+
+		for serial, r := range pending {
+			o, ok := onus[serial]
+			if !ok {
+				continue
+			}
+
+			st := strings.ToLower(o.UpgradeStatus.Status)
+			switch {
+			case strings.Contains(st, "fail") || strings.Contains(st, "error"):
+				reason := o.UpgradeStatus.FailureReason
+				if reason == "" {
+					reason = o.UpgradeStatus.Status
+				}
+				fail(r, reason)
+			case o.Connected != nil && *o.Connected && o.FirmwareVersion != nil && *o.FirmwareVersion == version:
+				r.ToVersion = *o.FirmwareVersion
+				r.Success = true
+			default:
+				continue
+			}
+			delete(pending, serial)
+		}
+	}
+
+	return failures
+}
@@ -1,5 +1,11 @@
 package godevman
 
+import (
+	"strings"
+
+	"github.com/aretaja/snmphelper"
+)
+
 // Adds generic UPS SNMP functionality to snmpCommon type
 type deviceUps struct {
 	snmpCommon
@@ -11,3 +17,266 @@ func (sd *deviceUps) SwVersion() (string, error) {
 	r, err := sd.getone(oid)
 	return r[oid].OctetString, err
 }
+
+// UPS-MIB well known alarms (.iso.org.dod.internet.mgmt.mib-2.upsMIB.upsObjects.upsAlarm.upsWellKnownAlarms)
+var upsWellKnownAlarms = map[string]string{
+	".1.3.6.1.2.1.33.1.6.3.1":  "batteryBad",
+	".1.3.6.1.2.1.33.1.6.3.2":  "onBattery",
+	".1.3.6.1.2.1.33.1.6.3.3":  "lowBattery",
+	".1.3.6.1.2.1.33.1.6.3.4":  "depletedBattery",
+	".1.3.6.1.2.1.33.1.6.3.5":  "tempBad",
+	".1.3.6.1.2.1.33.1.6.3.6":  "inputBad",
+	".1.3.6.1.2.1.33.1.6.3.7":  "outputBad",
+	".1.3.6.1.2.1.33.1.6.3.8":  "outputOverload",
+	".1.3.6.1.2.1.33.1.6.3.9":  "onBypass",
+	".1.3.6.1.2.1.33.1.6.3.10": "bypassBad",
+	".1.3.6.1.2.1.33.1.6.3.11": "outputOffAsRequested",
+	".1.3.6.1.2.1.33.1.6.3.12": "upsOffAsRequested",
+	".1.3.6.1.2.1.33.1.6.3.13": "chargerFailed",
+	".1.3.6.1.2.1.33.1.6.3.14": "upsOutputOff",
+	".1.3.6.1.2.1.33.1.6.3.15": "upsSystemOff",
+	".1.3.6.1.2.1.33.1.6.3.16": "fanFailure",
+	".1.3.6.1.2.1.33.1.6.3.17": "fuseFailure",
+	".1.3.6.1.2.1.33.1.6.3.18": "generalFault",
+	".1.3.6.1.2.1.33.1.6.3.19": "diagnosticTestFailed",
+	".1.3.6.1.2.1.33.1.6.3.20": "communicationsLost",
+	".1.3.6.1.2.1.33.1.6.3.21": "awaitingPower",
+	".1.3.6.1.2.1.33.1.6.3.22": "shutdownPending",
+	".1.3.6.1.2.1.33.1.6.3.23": "shutdownImminent",
+	".1.3.6.1.2.1.33.1.6.3.24": "testInProgress",
+}
+
+// Get info from .iso.org.dod.internet.mgmt.mib-2.upsMIB.upsObjects tree
+// Valid targets values: "All", "Battery", "Input", "Output", "Alarm"
+func (sd *deviceUps) Sensors(targets []string) (map[string]map[string]map[string]SensorVal, error) {
+	out := make(map[string]map[string]map[string]SensorVal)
+
+	oid := ".1.3.6.1.2.1.33.1"
+	inTable := oid + ".3.3.1."
+	outTable := oid + ".4.4.1."
+	alarmOid := oid + ".6.2.1.2"
+
+	t, err := sensorTargets(targets, "Battery", "Input", "Output", "Alarm")
+	if err != nil || len(t) == 0 {
+		return out, err
+	}
+
+	if t["Battery"] {
+		batStatus := map[int64]string{
+			1: "unknown",
+			2: "batteryNormal",
+			3: "batteryLow",
+			4: "batteryDepleted",
+		}
+
+		r, err := sd.getmulti(oid, []string{"2.1.0", "2.2.0", "2.3.0", "2.4.0", "2.5.0", "2.6.0", "2.7.0"})
+		if err != nil {
+			return out, err
+		}
+
+		for o, d := range r {
+			switch o {
+			case oid + ".2.1.0":
+				if s, ok := batStatus[d.Integer]; ok {
+					setSensorVal(out, "Battery", "Status", "Status", SensorVal{String: s, Bool: d.Integer == 2, IsSet: true})
+				}
+			case oid + ".2.2.0":
+				setSensorVal(out, "Battery", "Status", "SecondsOnBattery", signedSensorVal(d.Integer, "s", 1))
+			case oid + ".2.3.0":
+				setSensorVal(out, "Battery", "Status", "TimeLeft", signedSensorVal(d.Integer, "min", 1))
+			case oid + ".2.4.0":
+				setSensorVal(out, "Battery", "Status", "Capacity", signedSensorVal(d.Integer, "%", 1))
+			case oid + ".2.5.0":
+				setSensorVal(out, "Battery", "Status", "Voltage", signedSensorVal(d.Integer, "V", 10))
+			case oid + ".2.6.0":
+				setSensorVal(out, "Battery", "Status", "Current", signedSensorVal(d.Integer, "A", 10))
+			case oid + ".2.7.0":
+				setSensorVal(out, "Battery", "Status", "Temp", signedSensorVal(d.Integer, "°C", 1))
+			}
+		}
+	}
+
+	if t["Input"] {
+		r := make(snmphelper.SnmpOut)
+		for _, c := range []string{"2", "3", "4", "5"} {
+			cr, err := sd.getmulti(inTable+c, nil)
+			if err != nil {
+				return out, err
+			}
+			for k, v := range cr {
+				r[k] = v
+			}
+		}
+
+		for o, d := range r {
+			switch {
+			case strings.HasPrefix(o, inTable+"2."):
+				s := "Line" + strings.TrimPrefix(o, inTable+"2.")
+				setSensorVal(out, "Input", s, "Frequency", signedSensorVal(d.Integer, "Hz", 10))
+			case strings.HasPrefix(o, inTable+"3."):
+				s := "Line" + strings.TrimPrefix(o, inTable+"3.")
+				setSensorVal(out, "Input", s, "Voltage", signedSensorVal(d.Integer, "V", 1))
+			case strings.HasPrefix(o, inTable+"4."):
+				s := "Line" + strings.TrimPrefix(o, inTable+"4.")
+				setSensorVal(out, "Input", s, "Current", signedSensorVal(d.Integer, "A", 10))
+			case strings.HasPrefix(o, inTable+"5."):
+				s := "Line" + strings.TrimPrefix(o, inTable+"5.")
+				setSensorVal(out, "Input", s, "Power", signedSensorVal(d.Integer, "W", 1))
+			}
+		}
+	}
+
+	if t["Output"] {
+		outSource := map[int64]string{
+			1: "other",
+			2: "none",
+			3: "normal",
+			4: "bypass",
+			5: "battery",
+			6: "booster",
+			7: "reducer",
+		}
+
+		r, err := sd.getmulti(oid, []string{"4.1.0", "4.2.0"})
+		if err != nil {
+			return out, err
+		}
+		if r == nil {
+			r = make(snmphelper.SnmpOut)
+		}
+
+		for _, c := range []string{"2", "3", "4", "5"} {
+			cr, err := sd.getmulti(outTable+c, nil)
+			if err != nil {
+				return out, err
+			}
+			for k, v := range cr {
+				r[k] = v
+			}
+		}
+
+		for o, d := range r {
+			switch {
+			case o == oid+".4.1.0":
+				if s, ok := outSource[d.Integer]; ok {
+					setSensorVal(out, "Output", "Status", "Source", SensorVal{String: s, Bool: d.Integer == 3, IsSet: true})
+				}
+			case o == oid+".4.2.0":
+				setSensorVal(out, "Output", "Status", "Frequency", signedSensorVal(d.Integer, "Hz", 10))
+			case strings.HasPrefix(o, outTable+"2."):
+				s := "Line" + strings.TrimPrefix(o, outTable+"2.")
+				setSensorVal(out, "Output", s, "Voltage", signedSensorVal(d.Integer, "V", 1))
+			case strings.HasPrefix(o, outTable+"3."):
+				s := "Line" + strings.TrimPrefix(o, outTable+"3.")
+				setSensorVal(out, "Output", s, "Current", signedSensorVal(d.Integer, "A", 10))
+			case strings.HasPrefix(o, outTable+"4."):
+				s := "Line" + strings.TrimPrefix(o, outTable+"4.")
+				setSensorVal(out, "Output", s, "Power", signedSensorVal(d.Integer, "W", 1))
+			case strings.HasPrefix(o, outTable+"5."):
+				s := "Line" + strings.TrimPrefix(o, outTable+"5.")
+				setSensorVal(out, "Output", s, "Load", signedSensorVal(d.Integer, "%", 1))
+			}
+		}
+	}
+
+	if t["Alarm"] {
+		r, err := sd.snmpSession.Walk(alarmOid, true, true)
+		if err != nil && sd.handleErr(err) {
+			return out, err
+		}
+
+		// Set all well known alarms inactive and activate present ones
+		for _, name := range upsWellKnownAlarms {
+			setSensorVal(out, "Alarm", "Status", name, SensorVal{IsSet: true})
+		}
+
+		for _, d := range r {
+			name, ok := upsWellKnownAlarms[d.ObjectIdentifier]
+			if !ok {
+				name = d.ObjectIdentifier
+			}
+			setSensorVal(out, "Alarm", "Status", name, SensorVal{Bool: true, IsSet: true})
+		}
+	}
+
+	return out, nil
+}
+
+// UPS-MIB well known alarm severities
+var upsAlarmSeverity = map[string]string{
+	"batteryBad":           "critical",
+	"depletedBattery":      "critical",
+	"outputBad":            "critical",
+	"upsOutputOff":         "critical",
+	"upsSystemOff":         "critical",
+	"shutdownImminent":     "critical",
+	"lowBattery":           "major",
+	"tempBad":              "major",
+	"inputBad":             "major",
+	"outputOverload":       "major",
+	"bypassBad":            "major",
+	"chargerFailed":        "major",
+	"fanFailure":           "major",
+	"fuseFailure":          "major",
+	"generalFault":         "major",
+	"shutdownPending":      "major",
+	"onBattery":            "minor",
+	"onBypass":             "minor",
+	"diagnosticTestFailed": "minor",
+	"communicationsLost":   "minor",
+	"awaitingPower":        "minor",
+	"outputOffAsRequested": "warning",
+	"upsOffAsRequested":    "warning",
+	"testInProgress":       "warning",
+}
+
+// Get active alarms from .iso.org.dod.internet.mgmt.mib-2.upsMIB.upsObjects.upsAlarm.upsAlarmTable
+func (sd *deviceUps) Alarms() ([]*AlarmInfo, error) {
+	var out []*AlarmInfo
+
+	alarmTable := ".1.3.6.1.2.1.33.1.6.2.1."
+	descr, err := sd.snmpSession.Walk(alarmTable+"2", true, true)
+	if err != nil && sd.handleErr(err) {
+		return out, err
+	}
+
+	if len(descr) == 0 {
+		return out, nil
+	}
+
+	ts, err := sd.snmpSession.Walk(alarmTable+"3", true, true)
+	if err != nil && sd.handleErr(err) {
+		return out, err
+	}
+
+	sys, err := sd.System([]string{"UpTime"})
+	if err != nil {
+		return out, err
+	}
+
+	for i, d := range descr {
+		name, ok := upsWellKnownAlarms[d.ObjectIdentifier]
+		if !ok {
+			name = d.ObjectIdentifier
+		}
+
+		sev, ok := upsAlarmSeverity[name]
+		if !ok {
+			sev = "indeterminate"
+		}
+
+		a := &AlarmInfo{
+			Source:   ValString{Value: "UPS", IsSet: true},
+			Severity: ValString{Value: sev, IsSet: true},
+			Text:     ValString{Value: name, IsSet: true},
+		}
+
+		if t, ok := ts[i]; ok && sys.UpTime.IsSet {
+			a.setRaised(TicksTime(sys.UpTime.Value, t.TimeTicks))
+		}
+
+		out = append(out, a)
+	}
+
+	return out, nil
+}
@@ -1,5 +1,9 @@
 package godevman
 
+import (
+	"strings"
+)
+
 // Adds Valere specific SNMP functionality to snmpCommon type
 type deviceValere struct {
 	snmpCommon
@@ -11,3 +15,133 @@ func (sd *deviceValere) SwVersion() (string, error) {
 	r, err := sd.getone(oid)
 	return r[oid].OctetString, err
 }
+
+// Get info from .iso.org.dod.internet.private.enterprises.valere.vpwrDcPowerMIB tree
+// Valid targets values: "All", "Battery", "Rectifier", "Mains", "Load", "Alarm"
+func (sd *deviceValere) Sensors(targets []string) (map[string]map[string]map[string]SensorVal, error) {
+	out := make(map[string]map[string]map[string]SensorVal)
+
+	oid := ".1.3.6.1.4.1.13858"
+	idxs := map[string]string{
+		"systemVoltage":      "2.2.1.0",
+		"systemCurrent":      "2.2.2.0",
+		"batteryTemp":        "3.2.1.0",
+		"batteryCurrent":     "3.2.2.0",
+		"batteryCapacity":    "3.2.3.0",
+		"rectifiersNumber":   "2.2.4.0",
+		"rectifiersCapacity": "2.2.5.0",
+		"acStatus":           "2.2.6.0",
+	}
+
+	// vpwrModuleTable columns
+	modTable := oid + ".7.1.1.1."
+	modOids := map[string]string{
+		"modCurrent": modTable + "5",
+		"modStatus":  modTable + "4",
+	}
+
+	// vpwrAlarmTable columns
+	alarmTable := oid + ".2.3.1.1."
+	alarmOids := map[string]string{
+		"alarmDescr": alarmTable + "2",
+		"alarmState": alarmTable + "3",
+	}
+
+	groups := map[string][]string{
+		"Battery":   {"systemVoltage", "batteryTemp", "batteryCurrent", "batteryCapacity"},
+		"Rectifier": {"rectifiersNumber", "rectifiersCapacity"},
+		"Mains":     {"acStatus"},
+		"Load":      {"systemCurrent"},
+		"Alarm":     {},
+	}
+
+	t, err := sensorTargets(targets, "Battery", "Rectifier", "Mains", "Load", "Alarm")
+	if err != nil || len(t) == 0 {
+		return out, err
+	}
+
+	if rIdxs := sensorIdxs(t, groups, idxs); len(rIdxs) > 0 {
+		r, err := sd.getmulti(oid, rIdxs)
+		if err != nil {
+			return out, err
+		}
+
+		for o, d := range r {
+			if d.Vtype != "Integer" {
+				continue
+			}
+
+			switch o {
+			case oid + "." + idxs["systemVoltage"]:
+				// Value in mV
+				setSensorVal(out, "Battery", "Status", "Voltage", signedSensorVal(d.Integer, "V", 1000))
+			case oid + "." + idxs["systemCurrent"]:
+				// Value in mA
+				setSensorVal(out, "Load", "Status", "Current", signedSensorVal(d.Integer, "A", 1000))
+			case oid + "." + idxs["batteryTemp"]:
+				setSensorVal(out, "Battery", "Status", "Temp", signedSensorVal(d.Integer, "°C", 1))
+			case oid + "." + idxs["batteryCurrent"]:
+				// Value in mA
+				setSensorVal(out, "Battery", "Status", "Current", signedSensorVal(d.Integer, "A", 1000))
+			case oid + "." + idxs["batteryCapacity"]:
+				setSensorVal(out, "Battery", "Status", "Capacity", signedSensorVal(d.Integer, "%", 1))
+			case oid + "." + idxs["rectifiersNumber"]:
+				setSensorVal(out, "Rectifier", "Status", "Count", signedSensorVal(d.Integer, "", 1))
+			case oid + "." + idxs["rectifiersCapacity"]:
+				setSensorVal(out, "Rectifier", "Status", "Load", signedSensorVal(d.Integer, "%", 1))
+			case oid + "." + idxs["acStatus"]:
+				// 1 - ac ok, 2 - ac fail
+				setSensorVal(out, "Mains", "Status", "Ok", SensorVal{Bool: d.Integer == 1, IsSet: true})
+			}
+		}
+	}
+
+	// Per rectifier module values
+	if t["Rectifier"] {
+		for _, mo := range modOids {
+			r, err := sd.getmulti(mo, nil)
+			if err != nil {
+				return out, err
+			}
+
+			for o, d := range r {
+				i := strings.TrimPrefix(o, mo+".")
+				sName := "Module" + i
+				switch mo {
+				case modOids["modCurrent"]:
+					// Value in mA
+					setSensorVal(out, "Rectifier", sName, "Current", signedSensorVal(d.Integer, "A", 1000))
+				case modOids["modStatus"]:
+					// 1 - ok, 2 - fail, 3 - not present
+					setSensorVal(out, "Rectifier", sName, "Ok", SensorVal{Bool: d.Integer == 1, IsSet: true})
+				}
+			}
+		}
+	}
+
+	// Active alarms
+	if t["Alarm"] {
+		descr, err := sd.getmulti(alarmOids["alarmDescr"], nil)
+		if err != nil {
+			return out, err
+		}
+
+		state, err := sd.getmulti(alarmOids["alarmState"], nil)
+		if err != nil {
+			return out, err
+		}
+
+		for o, d := range descr {
+			i := strings.TrimPrefix(o, alarmOids["alarmDescr"]+".")
+			s, ok := state[alarmOids["alarmState"]+"."+i]
+			if !ok {
+				continue
+			}
+
+			// 1 - active, 2 - inactive
+			setSensorVal(out, "Alarm", "Status", strings.TrimSpace(d.OctetString), SensorVal{Bool: s.Integer == 1, IsSet: true})
+		}
+	}
+
+	return out, nil
+}
@@ -16,6 +16,8 @@ type deviceViola struct {
 // Make http Get request and return byte slice of body.
 // Argument string should contain remainder after base URL.
 func (sd *deviceViola) WebApiGet(params string) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
 	client := sd.webSession.client
 	if sd.webSession.client == nil {
 		// setup client
@@ -45,6 +47,8 @@ func (sd *deviceViola) WebApiGet(params string) ([]byte, error) {
 // Make http WebApiPostForm request and return byte slice of body.
 // Argument string should contain remainder after base URL.
 func (sd *deviceViola) WebApiPostForm(params string, rd url.Values) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
 	client := sd.webSession.client
 	if sd.webSession.client == nil {
 		// setup client
@@ -102,6 +106,11 @@ func (sd *deviceViola) getPhpSessid() (url.Values, error) {
 // Login via web API and stores web session in deviceViola.webSession.client.
 // Use this before use of methods which are accessing restricted device web API.
 func (sd *deviceViola) WebAuth(userPass []string) error {
+	if reuse, err := sd.webSession.lease(); err != nil || reuse {
+		return err
+	}
+	defer sd.webSession.leased()
+
 	// setup client
 	client, err := sd.webClient(nil)
 	if err != nil {
@@ -142,6 +151,11 @@ func (sd *deviceViola) WebAuth(userPass []string) error {
 // Logout via web API and delete web session from deviceEricssonMlPt.webSession.client.
 // Use this after use of methods which are accessing restricted device web API.
 func (sd *deviceViola) WebLogout() error {
+	if sd.webSession.unlease() {
+		return nil
+	}
+	defer sd.webSession.unleased()
+
 	if sd.webSession.client == nil {
 		return nil
 	}
@@ -215,12 +229,38 @@ func (sd *deviceViola) cliPrepare() (*CliParams, error) {
 	if sd.cliSession.params.LineEnd == "" {
 		params.LineEnd = "\n"
 	}
+	if sd.cliSession.params.Interact == nil {
+		params.Interact = []CliInteract{
+			{Re: `--More--(\(\d+%\))?\s*$`, Send: " ", NoLineEnd: true},
+		}
+	}
 
 	return params, nil
 }
 
+// Open persistent cli session. RunCmds will use it until CloseCli is called
+func (sd *deviceViola) OpenCli() error {
+	p, err := sd.cliPrepare()
+	if err != nil {
+		return err
+	}
+
+	if err := sd.cliLock(); err != nil {
+		return err
+	}
+	defer sd.cliUnlock()
+
+	return sd.openCli(p, sd.startCli)
+}
+
 // Execute cli commands
 func (sd *deviceViola) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
+	res, err := sd.RunCmdsStructured(c, o)
+	return CliTranscript(res), err
+}
+
+// Execute cli commands. Returns result per command
+func (sd *deviceViola) RunCmdsStructured(c []string, o *CliCmdOpts) ([]*CliCmdResult, error) {
 	if o == nil {
 		o = new(CliCmdOpts)
 	}
@@ -230,6 +270,11 @@ func (sd *deviceViola) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		return nil, err
 	}
 
+	if err := sd.cliLock(); err != nil {
+		return nil, err
+	}
+	defer sd.cliUnlock()
+
 	err = sd.startCli(p)
 	if err != nil {
 		return nil, err
@@ -242,7 +287,7 @@ func (sd *deviceViola) RunCmds(c []string, o *CliCmdOpts) ([]string, error) {
 		}
 	}
 
-	out, err := sd.cliCmds(c, o.ChkErr)
+	out, err := sd.cliCmdsRes(c, o.ChkErr, o.Interact...)
 	if err != nil {
 		err2 := sd.closeCli()
 		if err2 != nil {
@@ -310,15 +355,68 @@ func (sd *deviceViola) SwVersion() (string, error) {
 		"exit",
 	}
 
-	r, err := sd.RunCmds(cmds, &CliCmdOpts{ChkErr: true})
+	r, err := sd.RunCmdsStructured(cmds, &CliCmdOpts{ChkErr: true})
 	if err != nil {
 		return "", fmt.Errorf("cli command error: %v", err)
 	}
 
-	var rows []string
-	for _, s := range r {
-		rows = append(rows, SplitLineEnd(s)...)
+	rows := SplitLineEnd(r[0].Output)
+	if len(rows) == 0 {
+		return "", fmt.Errorf("no output from 'firmware -v'")
 	}
 
-	return rows[1], nil
+	return rows[0], nil
+}
+
+// Get running config (content of main configuration files)
+func (sd *deviceViola) RuningCfg() (string, error) {
+	return sd.cliConfig(sd.RunCmdsStructured, linuxCfgCmds(linuxCfgFiles), "exit")
+}
+
+// Backup main configuration files (tar archive) to tftp server
+func (sd *deviceViola) DoBackup() error {
+	host, err := sd.backupHost()
+	if err != nil {
+		return err
+	}
+	targetFile, t := sd.backupTarget(".tar.gz")
+
+	port, err := sd.backupRecvPort("69")
+	if err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	// Start embedded receiver if requested
+	rcv, err := sd.backupRecvStart("tftp")
+	if err != nil {
+		return sd.backupDone(host, targetFile, t, err)
+	}
+
+	tmpFile := "/tmp/godevman-backup.tar.gz"
+	cmds := []string{
+		"tar czf " + tmpFile + " " + strings.Join(linuxCfgFiles, " ") + " 2>/dev/null",
+		"tftp -p -l " + tmpFile + " -r " + targetFile + " " + host + " " + port,
+		"rm -f " + tmpFile,
+	}
+
+	// Some configuration files are readable by root only
+	priv := sd.cliSession != nil && len(sd.cliSession.params.Cred) > 2
+	_, err = sd.RunCmdsStructured(sd.cliExit(cmds, "exit"), &CliCmdOpts{ChkErr: true, Priv: priv})
+	if err != nil {
+		if rcv != nil {
+			rcv.stop()
+		}
+		return sd.backupDone(host, targetFile, t, fmt.Errorf("cli error: %v", err))
+	}
+
+	if rcv != nil {
+		err = sd.backupRecvFinish(rcv, targetFile)
+	}
+
+	return sd.backupDone(host, targetFile, t, err)
+}
+
+// Get info of last backup initiated by DoBackup
+func (sd *deviceViola) LastBackup() (*BackupInfo, error) {
+	return sd.backupInfo()
 }
@@ -18,6 +18,8 @@ type deviceViolaNoSNMP struct {
 // Make http Get request and return byte slice of body.
 // Argument string should contain request parameters.
 func (d *deviceViolaNoSNMP) WebApiGet(params string) ([]byte, error) {
+	defer d.webSession.serialize()()
+
 	client := d.webSession.client
 	if d.webSession.client == nil {
 		// setup client
@@ -47,6 +49,8 @@ func (d *deviceViolaNoSNMP) WebApiGet(params string) ([]byte, error) {
 // Make http WebApiPostForm request and return byte slice of body.
 // Argument string should contain remainder after base URL.
 func (sd *deviceViolaNoSNMP) WebApiPostForm(params string, rd url.Values) ([]byte, error) {
+	defer sd.webSession.serialize()()
+
 	client := sd.webSession.client
 	if sd.webSession.client == nil {
 		// setup client
@@ -77,6 +81,11 @@ func (sd *deviceViolaNoSNMP) WebApiPostForm(params string, rd url.Values) ([]byt
 // Login via web API and stores web session in deviceViolaNoSNMP.webSession.client.
 // Use this before use of methods which are accessing restricted device web API.
 func (sd *deviceViolaNoSNMP) WebAuth(userPass []string) error {
+	if reuse, err := sd.webSession.lease(); err != nil || reuse {
+		return err
+	}
+	defer sd.webSession.leased()
+
 	// setup client
 	client, err := sd.webClient(nil)
 	if err != nil {
@@ -132,6 +141,11 @@ func (sd *deviceViolaNoSNMP) WebAuth(userPass []string) error {
 // Logout via web API and delete web session from deviceEricssonMlPt.webSession.client.
 // Use this after use of methods which are accessing restricted device web API.
 func (sd *deviceViolaNoSNMP) WebLogout() error {
+	if sd.webSession.unlease() {
+		return nil
+	}
+	defer sd.webSession.unleased()
+
 	if sd.webSession.client == nil {
 		return nil
 	}
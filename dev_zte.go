@@ -0,0 +1,141 @@
+package godevman
+
+import (
+	"strings"
+
+	"github.com/aretaja/snmphelper"
+)
+
+// Adds ZTE specific SNMP functionality to snmpCommon type
+type deviceZte struct {
+	snmpCommon
+}
+
+// ZTE GPON ONU phase state values (ZTE-AN-GPON-SERVICE-MIB zxAnGponOnuPhaseState)
+var zteOnuPhaseState = map[int64]string{
+	1: "logging",
+	2: "los",
+	3: "syncMib",
+	4: "working",
+	5: "dyingGasp",
+	6: "authFailed",
+	7: "offline",
+}
+
+// Returns dBm value (0.001 dBm) of ZTE ONU optical power reading.
+// Second return value is false if reading is not valid.
+func zteOnuPower(v int64) (int64, bool) {
+	if v == 65535 || v < 0 {
+		return 0, false
+	}
+	if v > 30000 {
+		v = v - 65536
+	}
+
+	// 0.002 dBm per step from -30 dBm
+	return v*2 - 30000, true
+}
+
+// Get info from ZTE GPON MIBs
+// Returns OLT's GPON ONU info. Map keys are ONU serials
+func (sd *deviceZte) OnuInfo() (map[string]*OnuInfo, error) {
+	out := make(map[string]*OnuInfo)
+
+	oids := map[string]string{
+		"name":     ".1.3.6.1.4.1.3902.1012.3.28.1.1.2",
+		"serial":   ".1.3.6.1.4.1.3902.1012.3.28.1.1.5",
+		"state":    ".1.3.6.1.4.1.3902.1012.3.28.2.1.4",
+		"distance": ".1.3.6.1.4.1.3902.1012.3.11.4.1.2",
+		"rxPower":  ".1.3.6.1.4.1.3902.1012.3.50.12.1.1.10",
+		"txPower":  ".1.3.6.1.4.1.3902.1012.3.50.12.1.1.14",
+		"oltRx":    ".1.3.6.1.4.1.3902.1015.1010.11.2.1.2",
+	}
+
+	r, err := sd.walkCols(oids)
+	if err != nil {
+		return out, err
+	}
+
+	// ONU optical readings are indexed per ONU UNI. Use first UNI
+	for _, n := range []string{"rxPower", "txPower"} {
+		vr := make(snmphelper.SnmpOut)
+		for idx, v := range r[n] {
+			if strings.HasSuffix(idx, ".1") {
+				vr[strings.TrimSuffix(idx, ".1")] = v
+			}
+		}
+		r[n] = vr
+	}
+
+	ifs, err := sd.IfInfo([]string{"Descr"})
+	if err != nil {
+		return out, err
+	}
+
+	for idx, s := range r["serial"] {
+		// index is <PON port ifIndex>.<ONU id>
+		parts := strings.SplitN(idx, ".", 2)
+		if len(parts) != 2 || s.OctetString == "" {
+			continue
+		}
+
+		o := new(OnuInfo)
+
+		o.OltPort.Value = parts[0]
+		if i, ok := ifs[parts[0]]; ok && i.Descr.IsSet {
+			o.OltPort.Value = i.Descr.Value
+		}
+		o.OltPort.IsSet = true
+
+		if v, ok := r["name"][idx]; ok {
+			o.Name.Value = strings.TrimSpace(v.OctetString)
+			o.Name.IsSet = true
+		}
+		if v, ok := r["state"][idx]; ok {
+			o.State.Value = zteOnuPhaseState[v.Integer]
+			if o.State.Value == "" {
+				o.State.Value = "unknown"
+			}
+			o.State.IsSet = true
+			o.Online.Value = v.Integer == 4
+			o.Online.IsSet = true
+
+			// Agent doesn't provide last down cause. Derive it from phase state of offline ONU
+			switch v.Integer {
+			case 2:
+				o.DownCause.Value = "LOS"
+			case 5:
+				o.DownCause.Value = "dying-gasp"
+			case 6:
+				o.DownCause.Value = "authentication failed"
+			}
+			o.DownCause.IsSet = o.DownCause.Value != ""
+		}
+		if v, ok := r["distance"][idx]; ok && v.Integer >= 0 {
+			o.Distance = SensorVal{
+				Unit:    "m",
+				Divisor: 1,
+				Value:   uint64(v.Integer),
+				IsSet:   true,
+			}
+		}
+		if v, ok := r["rxPower"][idx]; ok {
+			if p, ok := zteOnuPower(v.Integer); ok {
+				o.RxPower = signedSensorVal(p, "dBm", 1000)
+			}
+		}
+		if v, ok := r["txPower"][idx]; ok {
+			if p, ok := zteOnuPower(v.Integer); ok {
+				o.TxPower = signedSensorVal(p, "dBm", 1000)
+			}
+		}
+		// OLT RX power (0.001 dBm). -80000 means no reading
+		if v, ok := r["oltRx"][idx]; ok && v.Integer > -80000 {
+			o.OltRxPower = signedSensorVal(v.Integer, "dBm", 1000)
+		}
+
+		out[GponSerial(s.OctetString)] = o
+	}
+
+	return out, nil
+}
@@ -7,6 +7,7 @@ require (
 	github.com/davecgh/go-spew v1.1.1
 	github.com/kr/pretty v0.3.1
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/pkg/sftp v1.13.6
 	github.com/praserx/ipconv v1.2.1
 	golang.org/x/crypto v0.14.0
 )
@@ -14,6 +15,7 @@ require (
 require (
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/google/goterm v0.0.0-20200907032337-555d40f16ae2 // indirect
+	github.com/kr/fs v0.1.0 // indirect
 	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231016165738-49dd2c1f3d0b // indirect
@@ -23,7 +25,7 @@ require (
 
 require (
 	github.com/google/goexpect v0.0.0-20210430020637-ab937bf7fd6f
-	github.com/gosnmp/gosnmp v1.36.1 // indirect
+	github.com/gosnmp/gosnmp v1.36.1
 	github.com/kr/text v0.2.0 // indirect
 	github.com/rogpeppe/go-internal v1.11.0 // indirect
 )
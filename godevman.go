@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aretaja/snmphelper"
@@ -77,6 +78,16 @@ type CliParams struct {
 	// Depends on device type
 	// Keep it as is if you are not sure
 	Timeout int
+	// Keepalive interval (sec) of persistent session (see OpenCli). Default 0 - disabled
+	Keepalive int
+	// Idle timeout (sec) of persistent session (see OpenCli). Default 0 - disabled
+	// Idle session will be closed and reopened on next use
+	IdleTimeout int
+	// Interactive prompts handling rules (pagers, confirmations, ...)
+	// Rules are checked in order before command prompt
+	// Default depends on device type and handles pagers only.
+	// Confirmations and password prompts must be answered per call (CliCmdOpts.Interact)
+	Interact []CliInteract
 }
 
 // CLI command exec options
@@ -87,6 +98,39 @@ type CliCmdOpts struct {
 	// Run commands in privilrged mode (applicable on some device types)
 	// Default false
 	Priv bool
+	// Additional interactive prompt rules for these commands.
+	// Checked before session rules (CliParams.Interact)
+	Interact []CliInteract
+}
+
+// Interactive cli prompt handling rule
+type CliInteract struct {
+	// Interactive prompt re pattern
+	Re string
+	// Response to send
+	Send string
+	// Send password (second element of CliParams.Cred) instead of Send
+	Pass bool
+	// Don't append line end to response (fe. pager continuation)
+	NoLineEnd bool
+}
+
+// Cli command result
+type CliCmdResult struct {
+	// Sent command
+	Cmd string
+	// Command output without command echo, pager artefacts, ANSI escape sequences and prompt
+	Output string
+	// Received data as is
+	Raw string
+	// Matched prompt (empty if not expected)
+	Prompt string
+	// Matched part of output if command error was detected
+	ErrMatch string
+	// Time from command send to prompt match
+	Duration time.Duration
+	// output was received
+	received bool
 }
 
 // Info needed for Device backup
@@ -98,8 +142,29 @@ type BackupParams struct {
 	DevIdent string
 	// Base path for backups (if device type needs it)
 	BasePath string
+	// Local directory for backups fetched from device (Mikrotik, Teltonika)
+	LocalDir string
 	// Credentials
+	// Embedded sftp receiver accepts these credentials
 	Cred []string
+	// Listen address of embedded backup receiver (fe. ":69" for tftp, ":22" for sftp/scp).
+	// If set, DoBackup runs embedded receiver and confirms receipt of backup file
+	// TargetIp must be address of this host. Only transfers from device ip are accepted.
+	// Cisco, Juniper, Moxa, Ceragon and Ruggedcom can't use non-default port (22 or 69)
+	RecvAddr string
+	// Max wait time (sec) for backup file. Default 60
+	RecvTimeout int
+	// Min acceptable size (bytes) of received backup file. Default 1
+	MinSize int
+	// PEM encoded private host key of embedded sftp receiver
+	// Random key will be generated if not set
+	HostKey []byte
+	// Storage backend of received backup files. Optional
+	Store BackupStore
+	// backup file received during last DoBackup
+	file *BackupFile
+	// result of last DoBackup
+	info *BackupInfo
 }
 
 // Parameters for new Device object initialization
@@ -117,6 +182,14 @@ type Dparams struct {
 	BackupParams BackupParams
 	SnmpCred     SnmpCred
 	CliParams    CliParams
+	// Share cli and web sessions with other device objects
+	// of same ip and credentials (see SessionPool).
+	// Persistent cli session (OpenCli) and configuration transaction are held by
+	// opening device object until CloseCli. Other device objects wait for it.
+	Pooled bool
+	// Management VLAN id. VLAN changes which would remove it are refused.
+	// Default is 1
+	MgmtVlan int
 }
 
 // Websession
@@ -125,6 +198,20 @@ type webSess struct {
 	client *http.Client
 	// web session credentials
 	cred []string
+	// serializes session lease and release
+	mu sync.Mutex
+	// serializes requests of pooled session
+	req sync.Mutex
+	// count of active leases of pooled session
+	refs int
+	// session slots of device (nil - unlimited)
+	slots chan struct{}
+	// max wait time for free session slot
+	wait time.Duration
+	// session holds slot
+	held bool
+	// session is shared via session pool
+	pooled bool
 }
 
 // Clisession
@@ -133,6 +220,32 @@ type cliSess struct {
 	client *expect.GExpect
 	// cli session parameters
 	params *CliParams
+	// (re)start function of persistent session
+	start func() error
+	// stops keepalive of persistent session
+	stop chan struct{}
+	// last use time of session
+	lastUsed time.Time
+	// serializes session use
+	mu sync.Mutex
+	// keep session open between commands runs
+	persistent bool
+	// session slots of device (nil - unlimited)
+	slots chan struct{}
+	// max wait time for free session slot
+	wait time.Duration
+	// session holds slot
+	held bool
+	// configuration transaction is open (see DevConfigurator)
+	cfgTx bool
+	// configuration at the start of transaction
+	cfgBase []string
+	// device object which holds persistent session (OpenCli) and configuration transaction
+	owner *device
+	// lease of pooled session. Held by owner until CloseCli or by other device object for single command run
+	lease chan struct{}
+	// lease is taken for current lock
+	leased bool
 }
 
 // Device object
@@ -155,6 +268,8 @@ type device struct {
 	sysObjectId string
 	// timezone related actions will use this.
 	timeZone string
+	// management VLAN id of device
+	mgmtVlan int
 	// Debug level
 	debug int
 	// Enable use of cache
@@ -198,6 +313,11 @@ func NewDevice(p *Dparams) (*device, error) {
 		d.backupParams.DevIdent = d.ip
 	}
 
+	d.mgmtVlan = 1
+	if p.MgmtVlan > 0 {
+		d.mgmtVlan = p.MgmtVlan
+	}
+
 	d.timeZone = "Europe/Tallinn"
 	if p.TimeZone != "" {
 		_, err := time.LoadLocation(p.TimeZone)
@@ -275,6 +395,16 @@ func NewDevice(p *Dparams) (*device, error) {
 		}
 	}
 
+	// Use shared sessions if requested
+	if p.Pooled {
+		if p.CliParams.Cred != nil {
+			d.cliSession = sessPool.cliSess(&d, &p.CliParams)
+		}
+		if p.WebCred != nil {
+			d.webSession = sessPool.webSess(&d, p.WebCred)
+		}
+	}
+
 	// Setup cache
 	d.cache = cache.New(10*time.Second, 10*time.Second)
 	d.useCache = true
@@ -341,6 +471,14 @@ func (d *device) Morph() interface{} {
 				snmpCommon{*d},
 			}
 			res = &md
+		case d.sysObjectId == ".1.3.6.1.4.1.2011.2.80" ||
+			d.sysObjectId == ".1.3.6.1.4.1.2011.2.248" ||
+			d.sysObjectId == ".1.3.6.1.4.1.2011.2.340" ||
+			d.sysObjectId == ".1.3.6.1.4.1.2011.2.342":
+			md := deviceHuawei{
+				snmpCommon{*d},
+			}
+			res = &md
 		case strings.HasPrefix(d.sysObjectId, ".1.3.6.1.4.1.2636.1.1.1.2."):
 			md := deviceJuniper{
 				snmpCommon{*d},
@@ -431,6 +569,11 @@ func (d *device) Morph() interface{} {
 				snmpCommon{*d},
 			}
 			res = &md
+		case strings.HasPrefix(d.sysObjectId, ".1.3.6.1.4.1.3902.1082.1001."):
+			md := deviceZte{
+				snmpCommon{*d},
+			}
+			res = &md
 		default:
 			res = &snmpCommon{*d}
 		}
@@ -486,12 +629,14 @@ type System struct {
 }
 
 // Interface info
+// Hc is true if octets and unicast packets counters are 64 bit counters
 type IfInfo struct {
 	Descr, Name, Alias, Mac, LastStr, TypeStr, AdminStr, OperStr ValString
 	Type, Mtu, Admin, Oper                                       ValI64
 	Speed, Last, InOctets, InPkts, InUcast, InMcast, InBcast, InDiscards,
 	InErrors, OutOctets, OutPkts, OutUcast, OutMcast, OutBcast, OutDiscards,
-	OutErrors ValU64
+	OutErrors, DiscontTime ValU64
+	Hc ValBool
 }
 
 // Interface stack info
@@ -519,6 +664,13 @@ type D1qVlanInfo struct {
 	Name  string
 }
 
+// Dot1Q VLAN port membership modes used by DevVlanWriter
+const (
+	VlanPortTagged   = "tagged"
+	VlanPortUntagged = "untagged"
+	VlanPortNone     = "none"
+)
+
 // IP info
 type IpInfo struct {
 	Mask  string
@@ -538,6 +690,15 @@ type BackupInfo struct {
 	Success              bool
 }
 
+// Active alarm info
+// Severity is one of "critical", "major", "minor", "warning", "indeterminate"
+// Raised is unix timestamp of alarm raise time
+type AlarmInfo struct {
+	Source, Severity, Text, RaisedStr ValString
+	Raised                            ValU64
+	Acked                             ValBool
+}
+
 // Radiolink radio interface info
 type RfInfo struct {
 	Name       ValString
@@ -550,6 +711,8 @@ type RfInfo struct {
 	PowerIn    ValF64
 	PowerOut   ValF64
 	Snr        ValF64
+	// Current adaptive modulation (fe. "512QAM")
+	Modulation ValString
 }
 
 type RauInfo struct {
@@ -570,6 +733,17 @@ type RlRadioIfInfo struct {
 	EntityIdx ValInt
 	Es        ValInt
 	Uas       ValInt
+	Ses       ValInt
+	Bbe       ValInt
+	// G.826 counters of current (interval 0) and previous 15 minute and 24 hour intervals
+	Perf15m, Perf24h []*G826Info
+}
+
+// G.826 performance counters of interval
+type G826Info struct {
+	// Interval number. 0 - current interval, 1 - previous interval, etc.
+	Interval          ValInt
+	Es, Ses, Bbe, Uas ValInt
 }
 
 // Radiolink FarEnd radio interface info
@@ -611,10 +785,14 @@ type OnuInfo struct {
 	Version    ValString
 	OltPort    ValString
 	Name       ValString
+	State      ValString
+	DownCause  ValString
 	Ports      map[string]OnuPort
 	TxBytes    SensorVal
 	TxPower    SensorVal
 	RxPower    SensorVal
+	LaserBias  SensorVal
+	OltRxPower SensorVal
 	Ram        SensorVal
 	Distance   SensorVal
 	CpuTemp    SensorVal
@@ -628,6 +806,63 @@ type OnuInfo struct {
 	Enabled    ValBool
 }
 
+// OLT PON port optical info
+type PonOptics struct {
+	Vendor, Part, Serial ValString
+	TxPower              SensorVal
+	RxPower              SensorVal
+	LaserBias            SensorVal
+	Temperature          SensorVal
+	Voltage              SensorVal
+	Present              ValBool
+	Los                  ValBool
+}
+
+// ONU settings change. Nil fields will not be changed
+type OnuSet struct {
+	Name    *string
+	Enabled *bool
+	// Bandwidth limits (kbit/s). 0 - no limit
+	DownLimit, UpLimit *int
+	// ONU mode ("router" or "bridge")
+	Mode *string
+	// WAN VLAN in router mode
+	WanVlan *int
+	// Port VLANs in bridge mode. Map keys are ONU port ids
+	PortVlans map[string]*OnuPortVlans
+}
+
+// ONU bridge mode port VLANs
+type OnuPortVlans struct {
+	Native  int
+	Include []int
+}
+
+// ONU firmware upgrade parameters
+type OnuUpgradeParams struct {
+	// Target firmware version. Required
+	Version string
+	// Limit upgrade to these ONU serials. Default all ONUs
+	Serials []string
+	// Number of ONUs upgraded simultaneously per PON port. Default 4
+	BatchSize int
+	// Stop upgrade if number of failed ONU upgrades reaches this. Default 1
+	MaxFailures int
+	// Upgrade status poll interval (sec). Default 10
+	PollInterval int
+	// Max upgrade time of ONU batch (sec). Default 900
+	Timeout int
+}
+
+// ONU firmware upgrade result
+type OnuUpgradeResult struct {
+	Serial, OltPort string
+	FromVersion     string
+	ToVersion       string
+	FailureReason   string
+	Success         bool
+}
+
 // Phase Sync info
 type PhaseSyncInfo struct {
 	SrcsState     map[string]string
@@ -657,6 +892,36 @@ type MobSignal struct {
 	Registration, Technology, Band, Operator, Ber, CellId, Signal, SignalBars, Imei, Sinr, Rssi, Rsrp, Rsrq SensorVal
 }
 
+// Mobile modem SIM and data usage info. Values are related to active SIM.
+// Values which are not supported by device type are not set (IsSet false)
+type MobInfo struct {
+	ActiveSim, Iccid, Imsi, Msisdn, SimState, PinState, Operator ValString
+	Roaming                                                      ValBool
+	// Data counters (B). Supported by Teltonika only
+	RxToday, TxToday, RxMonth, TxMonth SensorVal
+}
+
+// Mobile modem reset types used by DevMobWriter
+const (
+	// Reset cellular data session
+	MobResetSession = "session"
+	// Detach and reattach modem to network
+	MobResetReconnect = "reconnect"
+	// Power cycle modem
+	MobResetPower = "power"
+)
+
+// Mobile SIM settings change. Nil fields will not be changed
+type MobSimSet struct {
+	// SIM slot (fe. "1"). Active SIM if empty
+	Slot                  string
+	Apn, ApnUser, ApnPass *string
+	// APN authentication ("none", "pap" or "chap")
+	ApnAuth *string
+	// SIM PIN code. Empty string disables PIN usage
+	Pin *string
+}
+
 // Energy Readings
 type EReadings struct {
 	day, night SensorVal
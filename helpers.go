@@ -7,6 +7,9 @@ import (
 	"math/rand"
 	"net"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -727,3 +730,626 @@ func IntAbs(val int64) uint64 {
 	}
 	return uint64(val)
 }
+
+// Returns SensorVal for signed integer value.
+// Sign of value is carried by Divisor (negative divisor for negative value)
+func signedSensorVal(val int64, unit string, div int) SensorVal {
+	if div == 0 {
+		div = 1
+	}
+
+	out := SensorVal{
+		Unit:    unit,
+		Divisor: div,
+		Value:   IntAbs(val),
+		IsSet:   true,
+	}
+	if val < 0 {
+		out.Divisor = -div
+	}
+
+	return out
+}
+
+// Set sensor value in Sensors output. g - group, s - sensor set, n - sensor name
+func setSensorVal(out map[string]map[string]map[string]SensorVal, g, s, n string, v SensorVal) {
+	if out[g] == nil {
+		out[g] = make(map[string]map[string]SensorVal)
+	}
+	if out[g][s] == nil {
+		out[g][s] = make(map[string]SensorVal)
+	}
+	out[g][s][n] = v
+}
+
+// Parse Sensors targets. "All" selects all valid targets.
+// Returns set of requested targets or error on unknown target
+func sensorTargets(targets []string, valid ...string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	for _, t := range targets {
+		if t == "All" {
+			for _, v := range valid {
+				out[v] = true
+			}
+			continue
+		}
+
+		var ok bool
+		for _, v := range valid {
+			if t == v {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown target: %s", t)
+		}
+		out[t] = true
+	}
+
+	return out, nil
+}
+
+// Returns sorted unique snmp indexes of value names in requested target groups.
+// groups - value names of target, idxs - snmp indexes of value names
+func sensorIdxs(t map[string]bool, groups map[string][]string, idxs map[string]string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for g := range t {
+		for _, n := range groups[g] {
+			i, ok := idxs[n]
+			if !ok || seen[i] {
+				continue
+			}
+			seen[i] = true
+			out = append(out, i)
+		}
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+// Returns GPON ONU serial (fe. "HWTC1A2B3C4D") from 8 octet SNMP value
+// (4 octets vendor id and 4 octets vendor specific serial)
+func GponSerial(b string) string {
+	if len(b) != 8 {
+		return strings.ToUpper(strings.TrimSpace(b))
+	}
+
+	return b[:4] + fmt.Sprintf("%X", b[4:])
+}
+
+// Check mobile SIM settings change
+func mobSimCheck(s *MobSimSet) error {
+	if s == nil {
+		return fmt.Errorf("SIM settings are not defined")
+	}
+	if s.ApnAuth != nil {
+		switch *s.ApnAuth {
+		case "none", "pap", "chap":
+		default:
+			return fmt.Errorf("not valid APN authentication type: %s", *s.ApnAuth)
+		}
+	}
+	if s.Pin != nil && *s.Pin != "" {
+		if l := len(*s.Pin); l < 4 || l > 8 || strings.Trim(*s.Pin, "0123456789") != "" {
+			return fmt.Errorf("not valid SIM PIN code")
+		}
+	}
+
+	return nil
+}
+
+// Check mobile modem reset type
+func mobResetCheck(kind string) error {
+	switch kind {
+	case MobResetSession, MobResetReconnect, MobResetPower:
+		return nil
+	}
+
+	return fmt.Errorf("not valid modem reset type: %s", kind)
+}
+
+// Set or clear bit of port in portlist bitmap (see BitMap). Bitmap is extended if needed
+func bitMapSet(b []byte, port int, v bool) []byte {
+	i := (port - 1) / 8
+	for len(b) <= i {
+		b = append(b, 0)
+	}
+
+	mask := byte(0x80) >> uint((port-1)%8)
+	if v {
+		b[i] |= mask
+	} else {
+		b[i] &^= mask
+	}
+
+	return b
+}
+
+// Check VLAN id
+func vlanIdCheck(vid int) error {
+	if vid < 1 || vid > 4094 {
+		return fmt.Errorf("not valid VLAN id: %d", vid)
+	}
+
+	return nil
+}
+
+// Check that VLAN is present in current VLAN info
+func vlanExists(cur map[string]*D1qVlanInfo, vid int) error {
+	if err := vlanIdCheck(vid); err != nil {
+		return err
+	}
+	if _, ok := cur[strconv.Itoa(vid)]; !ok {
+		return fmt.Errorf("VLAN %d not found", vid)
+	}
+
+	return nil
+}
+
+// Check VLAN creation against current VLAN info
+func vlanAddCheck(cur map[string]*D1qVlanInfo, vid int) error {
+	if err := vlanIdCheck(vid); err != nil {
+		return err
+	}
+	if _, ok := cur[strconv.Itoa(vid)]; ok {
+		return fmt.Errorf("VLAN %d already exists", vid)
+	}
+
+	return nil
+}
+
+// Check VLAN removal against current VLAN info. Default and management VLAN can't be removed
+func (d *device) vlanDelCheck(cur map[string]*D1qVlanInfo, vid int) error {
+	if err := vlanExists(cur, vid); err != nil {
+		return err
+	}
+	if vid == 1 {
+		return fmt.Errorf("refusing to remove default VLAN 1")
+	}
+	if vid == d.mgmtVlan {
+		return fmt.Errorf("refusing to remove management VLAN %d", vid)
+	}
+
+	return nil
+}
+
+// Returns true if port is member of VLAN and if membership is untagged
+func vlanPortMember(cur map[string]*D1qVlanInfo, vid int, ifIdx string) (bool, bool) {
+	v, ok := cur[strconv.Itoa(vid)]
+	if !ok {
+		return false, false
+	}
+
+	for _, p := range v.Ports {
+		if strconv.Itoa(p.IfIdx) == ifIdx {
+			return true, p.UnTag
+		}
+	}
+
+	return false, false
+}
+
+// Check that management VLAN keeps member ports after change.
+// leave - ports leaving management VLAN, join - ports joining it. Map keys are ifIndexes
+func (d *device) vlanMgmtCheck(cur map[string]*D1qVlanInfo, leave, join map[string]bool) error {
+	v, ok := cur[strconv.Itoa(d.mgmtVlan)]
+	if !ok {
+		return nil
+	}
+
+	members := make(map[string]bool)
+	for _, p := range v.Ports {
+		members[strconv.Itoa(p.IfIdx)] = true
+	}
+
+	removed := false
+	for i := range leave {
+		if members[i] {
+			removed = true
+			delete(members, i)
+		}
+	}
+	if !removed {
+		return nil
+	}
+
+	for i := range join {
+		members[i] = true
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("refusing to remove all ports from management VLAN %d", d.mgmtVlan)
+	}
+
+	return nil
+}
+
+// Check VLAN port membership change against current VLAN info.
+// moved - ports which also leave their current untagged VLAN (fe. access port reassignment).
+// Last member port of management VLAN can't be removed.
+func (d *device) vlanPortsCheck(cur map[string]*D1qVlanInfo, vid int, set map[string]string, moved map[string]bool) error {
+	if err := vlanExists(cur, vid); err != nil {
+		return err
+	}
+	if len(set) == 0 {
+		return fmt.Errorf("no ports to change")
+	}
+
+	leave, join := make(map[string]bool), make(map[string]bool)
+	for i, m := range set {
+		if n, err := strconv.Atoi(i); err != nil || n < 1 {
+			return fmt.Errorf("not valid ifindex: %s", i)
+		}
+		switch m {
+		case VlanPortTagged, VlanPortUntagged, VlanPortNone:
+		default:
+			return fmt.Errorf("not valid VLAN port mode: %s", m)
+		}
+
+		switch {
+		case vid == d.mgmtVlan && m == VlanPortNone:
+			leave[i] = true
+		case vid == d.mgmtVlan:
+			join[i] = true
+		case moved[i]:
+			if _, untag := vlanPortMember(cur, d.mgmtVlan, i); untag {
+				leave[i] = true
+			}
+		}
+	}
+
+	return d.vlanMgmtCheck(cur, leave, join)
+}
+
+// Check port VLAN id change against current VLAN info.
+// Port which is untagged member of management VLAN leaves it if its VLAN id is changed.
+func (d *device) vlanPvidCheck(cur map[string]*D1qVlanInfo, set map[string]int) error {
+	if len(set) == 0 {
+		return fmt.Errorf("no ports to change")
+	}
+
+	leave := make(map[string]bool)
+	for i, vid := range set {
+		if n, err := strconv.Atoi(i); err != nil || n < 1 {
+			return fmt.Errorf("not valid ifindex: %s", i)
+		}
+		if err := vlanExists(cur, vid); err != nil {
+			return err
+		}
+
+		if vid != d.mgmtVlan {
+			if _, untag := vlanPortMember(cur, d.mgmtVlan, i); untag {
+				leave[i] = true
+			}
+		}
+	}
+
+	return d.vlanMgmtCheck(cur, leave, nil)
+}
+
+// Returns true if port is tagged member of any VLAN
+func vlanTrunkPort(cur map[string]*D1qVlanInfo, ifIdx int) bool {
+	for _, v := range cur {
+		for _, p := range v.Ports {
+			if p.IfIdx == ifIdx && !p.UnTag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns access ports which are set as untagged members of other VLAN.
+// Such ports leave their current VLAN on devices with access/trunk port modes.
+func vlanAccessMoves(cur map[string]*D1qVlanInfo, set map[string]string) map[string]bool {
+	out := make(map[string]bool)
+	for i, m := range set {
+		n, _ := strconv.Atoi(i)
+		if m == VlanPortUntagged && !vlanTrunkPort(cur, n) {
+			out[i] = true
+		}
+	}
+
+	return out
+}
+
+// Returns id of VLAN where port is untagged member. 0 if not found
+func vlanPortUntagged(cur map[string]*D1qVlanInfo, ifIdx int) int {
+	for v, i := range cur {
+		for _, p := range i.Ports {
+			if p.IfIdx == ifIdx && p.UnTag {
+				n, _ := strconv.Atoi(v)
+				return n
+			}
+		}
+	}
+
+	return 0
+}
+
+// Returns radio interface info with single RAU and RF entry for radio interface.
+// Name, descr and status values are taken from interface info. Map keys of RAU and RF are ifDescr.
+func rlRadio(idx string, i *IfInfo) (*RlRadioIfInfo, *RfInfo) {
+	r := &RlRadioIfInfo{Rau: make(map[string]*RauInfo)}
+	rf := new(RfInfo)
+
+	if n, err := strconv.Atoi(idx); err == nil {
+		r.IfIdx = ValInt{Value: n, IsSet: true}
+		rf.IfIdx = r.IfIdx
+	}
+
+	if i != nil {
+		r.Descr, rf.Descr = i.Descr, i.Descr
+		r.Name, rf.Name = i.Name, i.Name
+		r.AdmStat = i.AdminStr
+		r.OperStat, rf.Status = i.OperStr, i.OperStr
+	}
+	if !r.Descr.IsSet {
+		r.Descr = ValString{Value: idx, IsSet: true}
+		rf.Descr = r.Descr
+	}
+
+	r.Rau[r.Descr.Value] = &RauInfo{
+		Rf:    map[string]*RfInfo{r.Descr.Value: rf},
+		Descr: r.Descr,
+	}
+
+	return r, rf
+}
+
+// Returns float value of SensorVal. Second return value is false if value is not set
+func sensorFloat(v SensorVal) (float64, bool) {
+	if !v.IsSet {
+		return 0, false
+	}
+	if v.Divisor == 0 {
+		return float64(v.Value), true
+	}
+
+	return float64(v.Value) / float64(v.Divisor), true
+}
+
+// Returns time of event from snmp agent upTime and event TimeStamp (Time Ticks).
+// Returns current time if event TimeStamp is larger than upTime.
+func TicksTime(ut, lc uint64) time.Time {
+	t := time.Now()
+	if lc > ut {
+		return t
+	}
+
+	d := time.Duration((ut-lc)/100) * time.Second
+	return t.Add(-d)
+}
+
+// Returns time decoded from SNMP DateAndTime octets (8 or 11 octets)
+func DateAndTime(b []byte) (time.Time, error) {
+	if len(b) != 8 && len(b) != 11 {
+		return time.Time{}, fmt.Errorf("not valid DateAndTime length: %d", len(b))
+	}
+
+	loc := time.UTC
+	if len(b) == 11 {
+		offset := int(b[9])*3600 + int(b[10])*60
+		if b[8] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	year := int(b[0])<<8 | int(b[1])
+	t := time.Date(year, time.Month(b[2]), int(b[3]), int(b[4]), int(b[5]), int(b[6]),
+		int(b[7])*100000000, loc)
+
+	return t, nil
+}
+
+// ITU-ALARM-TC-MIB perceived severity values (used also by ALARM-MIB alarmModelState)
+var ituPerceivedSeverity = map[int64]string{
+	1: "cleared",
+	2: "indeterminate",
+	3: "critical",
+	4: "major",
+	5: "minor",
+	6: "warning",
+}
+
+// Sets alarm raise time fields
+func (a *AlarmInfo) setRaised(t time.Time) {
+	a.Raised.Value = uint64(t.Unix())
+	a.Raised.IsSet = true
+	a.RaisedStr.Value = t.Format(time.RFC3339)
+	a.RaisedStr.IsSet = true
+}
+
+// Returns line by line difference between a and b.
+// Every output line is prefixed with " " (unchanged), "-" (removed) or "+" (added).
+func DiffLines(a, b []string) []string {
+	var out []string
+
+	// Common prefix and suffix
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	for _, l := range a[:pre] {
+		out = append(out, " "+l)
+	}
+
+	ma := a[pre : len(a)-suf]
+	mb := b[pre : len(b)-suf]
+
+	// Avoid huge memory usage on large changes
+	if len(ma)*len(mb) > 4000000 {
+		for _, l := range ma {
+			out = append(out, "-"+l)
+		}
+		for _, l := range mb {
+			out = append(out, "+"+l)
+		}
+	} else {
+		// Longest common subsequence lengths
+		lcs := make([][]int, len(ma)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(ma) || j < len(mb) {
+			switch {
+			case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+				out = append(out, " "+ma[i])
+				i++
+				j++
+			case j == len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+				out = append(out, "-"+ma[i])
+				i++
+			default:
+				out = append(out, "+"+mb[j])
+				j++
+			}
+		}
+	}
+
+	for _, l := range a[len(a)-suf:] {
+		out = append(out, " "+l)
+	}
+
+	return out
+}
+
+// Returns only changed lines of DiffLines output as string
+func DiffChanges(a, b []string) string {
+	var out []string
+	for _, l := range DiffLines(a, b) {
+		if !strings.HasPrefix(l, " ") {
+			out = append(out, l)
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// Volatile configuration lines (timestamps, uptime, ntp clock-period, ...)
+var cfgVolatileRe = []*regexp.Regexp{
+	// Comment lines with date or time
+	regexp.MustCompile(`^\s*(!|#|;|//).*(\d{1,2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}|[a-z]{3}/\d{2}/\d{4})`),
+	// Comment lines with uptime
+	regexp.MustCompile(`(?i)^\s*(!|#|;|//).*up ?time`),
+	regexp.MustCompile(`^\s*ntp clock-period `),
+	regexp.MustCompile(`^\s*Building configuration`),
+	regexp.MustCompile(`^\s*Current configuration ?: \d+ bytes`),
+	regexp.MustCompile(`^\s*Using \d+ out of \d+ bytes`),
+	regexp.MustCompile(`^\s*## Last (changed|commit):`),
+}
+
+// Returns configuration with volatile lines, trailing spaces and empty leading and trailing lines removed.
+// Submit additional volatile line re patterns if needed. Returns error if pattern is not valid.
+func NormalizeCfg(cfg string, extra ...string) (string, error) {
+	res := cfgVolatileRe
+	for _, e := range extra {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return "", fmt.Errorf("not valid volatile line pattern %q: %v", e, err)
+		}
+		res = append(res[:len(res):len(res)], re)
+	}
+
+	var out []string
+L1:
+	for _, l := range SplitLineEnd(cfg) {
+		for _, re := range res {
+			if re.MatchString(l) {
+				continue L1
+			}
+		}
+		out = append(out, strings.TrimRight(l, " \t"))
+	}
+
+	return strings.Trim(strings.Join(out, "\n"), "\n") + "\n", nil
+}
+
+// Returns unified diff of a and b with ctx lines of context. Returns empty string if equal.
+// na, nb - names of a and b
+func UnifiedDiff(a, b []string, na, nb string, ctx int) string {
+	d := DiffLines(a, b)
+
+	// Indexes of changed lines
+	var ch []int
+	for i, l := range d {
+		if !strings.HasPrefix(l, " ") {
+			ch = append(ch, i)
+		}
+	}
+	if len(ch) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- " + na + "\n+++ " + nb + "\n")
+
+	// Line numbers of a and b at start of every diff line
+	la := make([]int, len(d)+1)
+	lb := make([]int, len(d)+1)
+	for i, l := range d {
+		la[i+1], lb[i+1] = la[i], lb[i]
+		if l[0] != '+' {
+			la[i+1]++
+		}
+		if l[0] != '-' {
+			lb[i+1]++
+		}
+	}
+
+	for n := 0; n < len(ch); {
+		start := ch[n] - ctx
+		if start < 0 {
+			start = 0
+		}
+
+		// Merge changes with overlapping context
+		end := ch[n]
+		for n < len(ch) && ch[n]-end <= 2*ctx {
+			end = ch[n]
+			n++
+		}
+		end += ctx + 1
+		if end > len(d) {
+			end = len(d)
+		}
+
+		ca, cb := la[end]-la[start], lb[end]-lb[start]
+		sa, sb2 := la[start]+1, lb[start]+1
+		if ca == 0 {
+			sa--
+		}
+		if cb == 0 {
+			sb2--
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", sa, ca, sb2, cb)
+		for _, l := range d[start:end] {
+			sb.WriteString(l + "\n")
+		}
+	}
+
+	return sb.String()
+}
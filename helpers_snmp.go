@@ -1,7 +1,10 @@
 package godevman
 
 import (
+	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/aretaja/snmphelper"
@@ -74,3 +77,208 @@ func (sd *snmpCommon) handleErr(err error) bool {
 	}
 	return true
 }
+
+// RFC 3877 ALARM-MIB alarmActiveTable columns
+const (
+	alarmActiveResourceId = ".1.3.6.1.2.1.118.1.2.2.1.9"
+	alarmActiveDescr      = ".1.3.6.1.2.1.118.1.2.2.1.10"
+	alarmActiveModelPtr   = ".1.3.6.1.2.1.118.1.2.2.1.12"
+)
+
+// Get active alarms from .iso.org.dod.internet.mgmt.mib-2.alarmMIB.alarmObjects.alarmActive.alarmActiveTable
+// (RFC 3877 ALARM-MIB)
+func (sd *snmpCommon) alarmMibAlarms() ([]*AlarmInfo, error) {
+	var out []*AlarmInfo
+
+	oids := map[string]string{
+		"resourceId": alarmActiveResourceId,
+		"descr":      alarmActiveDescr,
+		"modelPtr":   alarmActiveModelPtr,
+	}
+
+	r := make(map[string]snmphelper.SnmpOut)
+	for n, o := range oids {
+		res, err := sd.snmpSession.Walk(o, true, true)
+		if err != nil && sd.handleErr(err) {
+			return out, err
+		}
+		r[n] = res
+	}
+
+	for idx, d := range r["descr"] {
+		a := &AlarmInfo{
+			Text: ValString{Value: strings.TrimSpace(d.OctetString), IsSet: true},
+		}
+
+		if res, ok := r["resourceId"][idx]; ok {
+			a.Source = ValString{Value: res.ObjectIdentifier, IsSet: true}
+		}
+
+		// Model pointer points to alarmModelEntry which last index is alarmModelState
+		a.Severity = ValString{Value: "indeterminate", IsSet: true}
+		if m, ok := r["modelPtr"][idx]; ok {
+			p := strings.Split(m.ObjectIdentifier, ".")
+			if v, err := strconv.ParseInt(p[len(p)-1], 10, 64); err == nil {
+				if s, ok := ituPerceivedSeverity[v]; ok && v > 1 {
+					a.Severity.Value = s
+				}
+			}
+		}
+
+		// Index is <alarmListName>.<alarmActiveDateAndTime>.<alarmActiveIndex>
+		// where strings are length prefixed
+		p := strings.Split(idx, ".")
+		if nl, err := strconv.Atoi(p[0]); err == nil && len(p) > nl+2 {
+			p = p[nl+1:]
+			if dl, err := strconv.Atoi(p[0]); err == nil && len(p) > dl+1 {
+				b := make([]byte, 0, dl)
+				for _, v := range p[1 : dl+1] {
+					i, _ := strconv.Atoi(v)
+					b = append(b, byte(i))
+				}
+
+				if t, err := DateAndTime(b); err == nil {
+					a.setRaised(t)
+				}
+			}
+		}
+
+		out = append(out, a)
+	}
+
+	return out, nil
+}
+
+// Walk table columns. cols - map of result names and column oids.
+// Returns walk results (with column oid stripped from keys) per result name
+func (sd *snmpCommon) walkCols(cols map[string]string) (map[string]snmphelper.SnmpOut, error) {
+	out := make(map[string]snmphelper.SnmpOut)
+	for n, o := range cols {
+		r, err := sd.snmpSession.Walk(o, true, true)
+		if err != nil && sd.handleErr(err) {
+			return out, err
+		}
+		out[n] = r
+	}
+
+	return out, nil
+}
+
+// Returns G.826 interval counters from walked performance table columns ("es", "ses", "bbe", "uas").
+// Column indexes must be <radio index>.<interval number>.
+// Returns counters per radio index sorted by interval number.
+func g826Intervals(t map[string]snmphelper.SnmpOut) map[string][]*G826Info {
+	ivs := make(map[string]map[int]*G826Info)
+	for col, r := range t {
+		for idx, v := range r {
+			p := strings.LastIndex(idx, ".")
+			if p < 0 {
+				continue
+			}
+			n, err := strconv.Atoi(idx[p+1:])
+			if err != nil {
+				continue
+			}
+
+			rIdx := idx[:p]
+			if ivs[rIdx] == nil {
+				ivs[rIdx] = make(map[int]*G826Info)
+			}
+			g, ok := ivs[rIdx][n]
+			if !ok {
+				g = &G826Info{Interval: ValInt{Value: n, IsSet: true}}
+				ivs[rIdx][n] = g
+			}
+
+			c, ok := snmpRawUint(v.Raw)
+			if !ok {
+				continue
+			}
+			val := ValInt{Value: int(c), IsSet: true}
+			switch col {
+			case "es":
+				g.Es = val
+			case "ses":
+				g.Ses = val
+			case "bbe":
+				g.Bbe = val
+			case "uas":
+				g.Uas = val
+			}
+		}
+	}
+
+	out := make(map[string][]*G826Info)
+	for rIdx, m := range ivs {
+		l := make([]*G826Info, 0, len(m))
+		for _, g := range m {
+			l = append(l, g)
+		}
+		sort.Slice(l, func(i, j int) bool { return l[i].Interval.Value < l[j].Interval.Value })
+		out[rIdx] = l
+	}
+
+	return out
+}
+
+// Returns unsigned value of raw SNMP value (integer, counter, gauge or numeric string).
+// Second return value is false if value is not numeric or negative
+func snmpRawUint(raw interface{}) (uint64, bool) {
+	switch v := raw.(type) {
+	case int:
+		if v >= 0 {
+			return uint64(v), true
+		}
+	case uint:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	case []byte:
+		if n, err := strconv.ParseUint(strings.TrimSpace(string(v)), 10, 64); err == nil {
+			return n, true
+		}
+	}
+
+	return 0, false
+}
+
+// Returns key-value map from "key: value" lines of cli output
+func colonParser(s string) map[string]string {
+	out := make(map[string]string)
+	for _, l := range SplitLineEnd(s) {
+		kv := strings.SplitN(l, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		k := strings.TrimSpace(kv[0])
+		if k == "" {
+			continue
+		}
+		out[k] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+	}
+
+	return out
+}
+
+// Returns ifDescr of interfaces. Map keys are ifIndexes
+func (sd *snmpCommon) ifDescrs(idx []string) (map[string]string, error) {
+	out := make(map[string]string)
+
+	r, err := sd.IfInfo([]string{"Descr"}, idx...)
+	if err != nil {
+		return out, fmt.Errorf("ifinfo error: %v", err)
+	}
+
+	for _, i := range idx {
+		d, ok := r[i]
+		if !ok || !d.Descr.IsSet || d.Descr.Value == "" {
+			return out, fmt.Errorf("interface with ifindex %s not found", i)
+		}
+		out[i] = d.Descr.Value
+	}
+
+	return out, nil
+}
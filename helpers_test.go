@@ -0,0 +1,28 @@
+package godevman
+
+import "testing"
+
+func TestSensorFloat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   SensorVal
+		want float64
+		ok   bool
+	}{
+		{"not set", SensorVal{Value: 5, Divisor: 1}, 0, false},
+		{"zero divisor", SensorVal{Value: 5, IsSet: true}, 5, true},
+		{"positive", signedSensorVal(2345, "V", 100), 23.45, true},
+		{"negative", signedSensorVal(-2345, "dBm", 100), -23.45, true},
+		{"negative without divisor", signedSensorVal(-7, "°C", 0), -7, true},
+		{"negative divisor", SensorVal{Value: 105, Divisor: -10, IsSet: true}, -10.5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := sensorFloat(tt.in)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("sensorFloat() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
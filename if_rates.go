@@ -0,0 +1,223 @@
+package godevman
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Interface counters snapshot
+type IfSnapshot struct {
+	// Snapshot time
+	Time time.Time
+	// Interface info (map keys are ifIndexes)
+	If map[string]*IfInfo
+	// sysUpTime of device (Time Ticks)
+	UpTime ValU64
+}
+
+// Interface rates calculated from two successive snapshots
+// Bps values are bits per second, Pps values packets per second,
+// Util values are utilization percentage of interface speed.
+type IfRate struct {
+	InBps, OutBps, InPps, OutPps, InErrors, OutErrors, InDiscards, OutDiscards,
+	InUtil, OutUtil ValF64
+	// Time between snapshots (sec)
+	Interval float64
+	// Counters discontinuity detected (device reboot, counter reset or ifCounterDiscontinuityTime change)
+	// Rates are not set if true
+	Discontinuity bool
+	// At least one 32 bit counter wrapped between snapshots
+	Wrapped bool
+}
+
+// Stateful interface rates calculator. Keeps last snapshot per device.
+type IfRateEngine struct {
+	mu   sync.Mutex
+	prev map[string]*IfSnapshot
+}
+
+// Initialize new interface rates calculator
+func NewIfRateEngine() *IfRateEngine {
+	return &IfRateEngine{
+		prev: make(map[string]*IfSnapshot),
+	}
+}
+
+// Calculate interface rates from submitted and previous snapshot of device.
+// dev - device identifier (fe. ip).
+// Returns nil map on first snapshot of device.
+func (e *IfRateEngine) Update(dev string, s *IfSnapshot) (map[string]*IfRate, error) {
+	if s == nil || s.If == nil {
+		return nil, fmt.Errorf("empty snapshot")
+	}
+
+	e.mu.Lock()
+	prev, ok := e.prev[dev]
+	e.prev[dev] = s
+	e.mu.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	return IfRates(prev, s)
+}
+
+// Remove device state
+func (e *IfRateEngine) Forget(dev string) {
+	e.mu.Lock()
+	delete(e.prev, dev)
+	e.mu.Unlock()
+}
+
+// Calculate interface rates from two successive snapshots
+func IfRates(prev, cur *IfSnapshot) (map[string]*IfRate, error) {
+	out := make(map[string]*IfRate)
+
+	interval := cur.Time.Sub(prev.Time).Seconds()
+	if interval <= 0 {
+		return out, fmt.Errorf("not valid snapshot interval: %.3f sec", interval)
+	}
+
+	// Device reboot or sysUpTime wrap
+	reset := prev.UpTime.IsSet && cur.UpTime.IsSet && cur.UpTime.Value < prev.UpTime.Value
+
+	for i, c := range cur.If {
+		r := &IfRate{Interval: interval}
+		out[i] = r
+
+		p, ok := prev.If[i]
+		if !ok || reset ||
+			(p.DiscontTime.IsSet && c.DiscontTime.IsSet && p.DiscontTime.Value != c.DiscontTime.Value) {
+			r.Discontinuity = true
+			continue
+		}
+
+		// Returns counter delta. Second return value is false on discontinuity
+		delta := func(pv, cv ValU64, hc bool) (uint64, bool) {
+			if cv.Value >= pv.Value {
+				return cv.Value - pv.Value, true
+			}
+
+			// 64 bit counters will not wrap in practice
+			if hc || pv.Value > math.MaxUint32 {
+				return 0, false
+			}
+
+			r.Wrapped = true
+			return math.MaxUint32 - pv.Value + cv.Value + 1, true
+		}
+
+		// Sets rate value and returns delta
+		rate := func(v *ValF64, pv, cv ValU64, hc bool, mul float64) (uint64, bool) {
+			if !pv.IsSet || !cv.IsSet {
+				return 0, false
+			}
+
+			d, ok := delta(pv, cv, hc)
+			if !ok {
+				r.Discontinuity = true
+				return 0, false
+			}
+
+			v.Value = float64(d) * mul / interval
+			v.IsSet = true
+			return d, true
+		}
+
+		hc := c.Hc.Value
+		rate(&r.InBps, p.InOctets, c.InOctets, hc, 8)
+		rate(&r.OutBps, p.OutOctets, c.OutOctets, hc, 8)
+		rate(&r.InErrors, p.InErrors, c.InErrors, false, 1)
+		rate(&r.OutErrors, p.OutErrors, c.OutErrors, false, 1)
+		rate(&r.InDiscards, p.InDiscards, c.InDiscards, false, 1)
+		rate(&r.OutDiscards, p.OutDiscards, c.OutDiscards, false, 1)
+
+		// Packet rates. Use total packets counter if present, otherwise sum of ucast, mcast and bcast counters
+		pps := func(v *ValF64, pPkts, cPkts ValU64, pc, cc [3]ValU64) {
+			if pPkts.IsSet && cPkts.IsSet {
+				rate(v, pPkts, cPkts, hc, 1)
+				return
+			}
+
+			var sum uint64
+			for n := range pc {
+				// Multicast and broadcast counters are from ifXTable (64 bit)
+				d, ok := rate(new(ValF64), pc[n], cc[n], hc || n > 0, 1)
+				if !ok {
+					continue
+				}
+				sum += d
+				v.IsSet = true
+			}
+
+			if v.IsSet {
+				v.Value = float64(sum) / interval
+			}
+		}
+
+		pps(&r.InPps, p.InPkts, c.InPkts,
+			[3]ValU64{p.InUcast, p.InMcast, p.InBcast}, [3]ValU64{c.InUcast, c.InMcast, c.InBcast})
+		pps(&r.OutPps, p.OutPkts, c.OutPkts,
+			[3]ValU64{p.OutUcast, p.OutMcast, p.OutBcast}, [3]ValU64{c.OutUcast, c.OutMcast, c.OutBcast})
+
+		if r.Discontinuity {
+			*r = IfRate{Interval: interval, Discontinuity: true}
+			continue
+		}
+
+		// Utilization
+		if c.Speed.IsSet && c.Speed.Value > 0 {
+			if r.InBps.IsSet {
+				r.InUtil.Value = r.InBps.Value * 100 / float64(c.Speed.Value)
+				r.InUtil.IsSet = true
+			}
+			if r.OutBps.IsSet {
+				r.OutUtil.Value = r.OutBps.Value * 100 / float64(c.Speed.Value)
+				r.OutUtil.IsSet = true
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// Take two interface counters snapshots with submitted interval and return calculated interface rates.
+// Interval must be longer than device object cache expiration time (10s) on device types
+// which are using cache for interface statistics.
+func SampleIfRates(d interface {
+	DevSysReader
+	DevIfReader
+}, interval time.Duration, idx ...string) (map[string]*IfRate, error) {
+	var snaps [2]*IfSnapshot
+
+	targets := []string{
+		"Speed", "InOctets", "InPkts", "InUcast", "InMcast", "InBcast", "InDiscards", "InErrors",
+		"OutOctets", "OutPkts", "OutUcast", "OutMast", "OutBcast", "OutDiscards", "OutErrors", "Discont",
+	}
+
+	for n := range snaps {
+		if n > 0 {
+			time.Sleep(interval)
+		}
+
+		s := &IfSnapshot{Time: time.Now()}
+
+		sys, err := d.System([]string{"UpTime"})
+		if err != nil {
+			return nil, err
+		}
+		s.UpTime = sys.UpTime
+
+		s.If, err = d.IfInfo(targets, idx...)
+		if err != nil {
+			return nil, err
+		}
+
+		snaps[n] = s
+	}
+
+	return IfRates(snaps[0], snaps[1])
+}
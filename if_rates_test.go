@@ -0,0 +1,77 @@
+package godevman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIfRates(t *testing.T) {
+	u64 := func(v uint64) ValU64 { return ValU64{Value: v, IsSet: true} }
+	f64 := func(v float64) ValF64 { return ValF64{Value: v, IsSet: true} }
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		pUp, cUp  ValU64
+		prev, cur IfInfo
+		want      IfRate
+	}{
+		{
+			name: "no wrap",
+			pUp:  u64(1000), cUp: u64(1800),
+			prev: IfInfo{InOctets: u64(1000), OutOctets: u64(2000)},
+			cur:  IfInfo{InOctets: u64(2296), OutOctets: u64(4592), Speed: u64(12960)},
+			want: IfRate{InBps: f64(1296), OutBps: f64(2592), InUtil: f64(10), OutUtil: f64(20), Interval: 8},
+		},
+		{
+			name: "counter32 wrap",
+			pUp:  u64(1000), cUp: u64(1800),
+			prev: IfInfo{InOctets: u64(4294967000)},
+			cur:  IfInfo{InOctets: u64(1000), Speed: u64(12960)},
+			want: IfRate{InBps: f64(1296), InUtil: f64(10), Interval: 8, Wrapped: true},
+		},
+		{
+			name: "counter64 wrap",
+			pUp:  u64(1000), cUp: u64(1800),
+			prev: IfInfo{InOctets: u64(5000), OutOctets: u64(100), Hc: ValBool{Value: true, IsSet: true}},
+			cur:  IfInfo{InOctets: u64(100), OutOctets: u64(200), Hc: ValBool{Value: true, IsSet: true}},
+			want: IfRate{Interval: 8, Discontinuity: true},
+		},
+		{
+			name: "sysUpTime reset",
+			pUp:  u64(100000), cUp: u64(50),
+			prev: IfInfo{InOctets: u64(1000)},
+			cur:  IfInfo{InOctets: u64(2296)},
+			want: IfRate{Interval: 8, Discontinuity: true},
+		},
+		{
+			name: "discontinuity time change",
+			pUp:  u64(1000), cUp: u64(1800),
+			prev: IfInfo{InOctets: u64(1000), DiscontTime: u64(0)},
+			cur:  IfInfo{InOctets: u64(2296), DiscontTime: u64(1500)},
+			want: IfRate{Interval: 8, Discontinuity: true},
+		},
+		{
+			name: "zero speed",
+			pUp:  u64(1000), cUp: u64(1800),
+			prev: IfInfo{InOctets: u64(1000)},
+			cur:  IfInfo{InOctets: u64(2296), Speed: u64(0)},
+			want: IfRate{InBps: f64(1296), Interval: 8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := &IfSnapshot{Time: t0, UpTime: tt.pUp, If: map[string]*IfInfo{"1": &tt.prev}}
+			cur := &IfSnapshot{Time: t0.Add(8 * time.Second), UpTime: tt.cUp, If: map[string]*IfInfo{"1": &tt.cur}}
+
+			got, err := IfRates(prev, cur)
+			if err != nil {
+				t.Fatalf("IfRates() error = %v", err)
+			}
+			if got["1"] == nil || *got["1"] != tt.want {
+				t.Errorf("IfRates() = %+v, want %+v", got["1"], tt.want)
+			}
+		})
+	}
+}
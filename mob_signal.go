@@ -0,0 +1,295 @@
+package godevman
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mobile network technologies used by signal classification
+const (
+	MobTechGsm     = "gsm"
+	MobTechUmts    = "umts"
+	MobTechLte     = "lte"
+	MobTechNr      = "nr"
+	MobTechUnknown = "unknown"
+)
+
+// Mobile technology generations. Used for fallback detection
+var mobTechGen = map[string]int{
+	MobTechGsm:  2,
+	MobTechUmts: 3,
+	MobTechLte:  4,
+	MobTechNr:   5,
+}
+
+// Mobile signal quality
+type MobQuality struct {
+	// Normalized technology (MobTech*)
+	Technology string
+	// Quality class ("excellent", "good", "fair", "poor" or "no signal")
+	Class string
+	// Normalized signal level 0-5
+	Bars int
+	// Measurement used for classification (fe. "rsrp")
+	Metric string
+}
+
+// Signal level thresholds (dBm or dB) for bars 5, 4, 3, 2 and 1
+type mobThresholds [5]float64
+
+var (
+	mobRsrpLevels = mobThresholds{-80, -90, -100, -110, -120}
+	mobSinrLevels = mobThresholds{20, 13, 5, 0, -5}
+	mobRssiLevels = map[string]mobThresholds{
+		MobTechGsm:  {-70, -85, -95, -105, -110},
+		MobTechUmts: {-70, -85, -95, -100, -110},
+		MobTechLte:  {-65, -75, -85, -95, -105},
+		MobTechNr:   {-65, -75, -85, -95, -105},
+	}
+)
+
+// Returns bars for value
+func (t mobThresholds) bars(v float64) int {
+	for i, l := range t {
+		if v >= l {
+			return 5 - i
+		}
+	}
+
+	return 0
+}
+
+// Returns normalized technology (MobTech*) from technology reported by device
+func MobTechnology(s string) string {
+	// "E-UTRAN", "LTE-A", "NR5G-NSA", ...
+	s = strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(s))
+	switch {
+	case strings.Contains(s, "5g") || strings.Contains(s, "nr"):
+		return MobTechNr
+	case strings.Contains(s, "lte") || strings.Contains(s, "eutran") || strings.Contains(s, "4g"):
+		return MobTechLte
+	case strings.Contains(s, "umts") || strings.Contains(s, "wcdma") || strings.Contains(s, "hs") ||
+		strings.Contains(s, "utran") || strings.Contains(s, "3g"):
+		return MobTechUmts
+	case strings.Contains(s, "gsm") || strings.Contains(s, "edge") || strings.Contains(s, "gprs") ||
+		strings.Contains(s, "2g"):
+		return MobTechGsm
+	}
+
+	return MobTechUnknown
+}
+
+// Returns quality class for bars
+func mobClass(bars int) string {
+	switch {
+	case bars >= 5:
+		return "excellent"
+	case bars == 4:
+		return "good"
+	case bars == 3:
+		return "fair"
+	case bars > 0:
+		return "poor"
+	}
+
+	return "no signal"
+}
+
+// Classify mobile signal.
+// LTE and 5G NR are classified by RSRP and SINR (lower result is used), GSM and UMTS by RSSI.
+// Device reported signal bars are used if signal levels are missing.
+func MobClassify(s MobSignal) *MobQuality {
+	q := &MobQuality{Technology: MobTechnology(s.Technology.String)}
+
+	rssi, rssiOk := sensorFloat(s.Rssi)
+	if !rssiOk && s.Signal.Unit == "dBm" {
+		rssi, rssiOk = sensorFloat(s.Signal)
+	}
+	rsrp, rsrpOk := sensorFloat(s.Rsrp)
+	sinr, sinrOk := sensorFloat(s.Sinr)
+
+	lte := q.Technology == MobTechLte || q.Technology == MobTechNr
+
+	switch {
+	case lte && rsrpOk:
+		q.Bars, q.Metric = mobRsrpLevels.bars(rsrp), "rsrp"
+		if sinrOk {
+			if b := mobSinrLevels.bars(sinr); b < q.Bars {
+				q.Bars, q.Metric = b, "sinr"
+			}
+		}
+	case rssiOk:
+		t, ok := mobRssiLevels[q.Technology]
+		if !ok {
+			t = mobRssiLevels[MobTechGsm]
+		}
+		q.Bars, q.Metric = t.bars(rssi), "rssi"
+	case s.SignalBars.IsSet:
+		// Device reported level (0-4)
+		b, _ := sensorFloat(s.SignalBars)
+		q.Bars, q.Metric = int(math.Round(b*5/4)), "bars"
+		if q.Bars > 5 {
+			q.Bars = 5
+		}
+	default:
+		q.Metric = "none"
+	}
+
+	q.Class = mobClass(q.Bars)
+
+	return q
+}
+
+// Returns cell id of mobile signal
+func mobCellId(s MobSignal) string {
+	if !s.CellId.IsSet {
+		return ""
+	}
+	if s.CellId.String != "" {
+		return s.CellId.String
+	}
+
+	return strconv.FormatUint(s.CellId.Value, 10)
+}
+
+// Mobile signal history sample
+type MobSignalSample struct {
+	Time    time.Time
+	Signal  MobSignal
+	Quality *MobQuality
+	CellId  string
+}
+
+// Returns true if cell id changed or technology fell back to older generation between two samples
+func mobCompare(p, c *MobSignalSample) (bool, bool) {
+	handover := p.CellId != "" && c.CellId != "" && p.CellId != c.CellId
+
+	pg, cg := mobTechGen[p.Quality.Technology], mobTechGen[c.Quality.Technology]
+	fallback := pg > 0 && cg > 0 && cg < pg
+
+	return handover, fallback
+}
+
+// Mobile signal change between two successive polls
+type MobSignalChange struct {
+	Quality *MobQuality
+	// Bars change since previous poll
+	BarsDelta int
+	// Cell id changed
+	Handover           bool
+	PrevCellId, CellId string
+	// Technology changed to older generation (fe. LTE -> UMTS)
+	Fallback       bool
+	PrevTechnology string
+}
+
+// Stateful mobile signal history. Keeps submitted number of samples per modem.
+type MobSignalHistory struct {
+	mu   sync.Mutex
+	size int
+	hist map[string][]*MobSignalSample
+}
+
+// Initialize new mobile signal history. size - number of samples kept per modem (default 96)
+func NewMobSignalHistory(size int) *MobSignalHistory {
+	if size <= 0 {
+		size = 96
+	}
+
+	return &MobSignalHistory{
+		size: size,
+		hist: make(map[string][]*MobSignalSample),
+	}
+}
+
+// Returns history key of modem
+func mobHistKey(dev, modem string) string {
+	return dev + "/" + modem
+}
+
+// Add mobile signal readings (MobSignal) of device to history and compare them with previous samples.
+// dev - device identifier (fe. ip). Returns changes per modem. Previous values are not set on first sample of modem.
+func (h *MobSignalHistory) Update(dev string, t time.Time, sig map[string]MobSignal) (map[string]*MobSignalChange, error) {
+	if sig == nil {
+		return nil, fmt.Errorf("empty mobile signal readings")
+	}
+
+	out := make(map[string]*MobSignalChange)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for m, s := range sig {
+		cur := &MobSignalSample{
+			Time:    t,
+			Signal:  s,
+			Quality: MobClassify(s),
+			CellId:  mobCellId(s),
+		}
+		c := &MobSignalChange{
+			Quality: cur.Quality,
+			CellId:  cur.CellId,
+		}
+		out[m] = c
+
+		k := mobHistKey(dev, m)
+		hist := h.hist[k]
+		if l := len(hist); l > 0 {
+			prev := hist[l-1]
+			c.BarsDelta = cur.Quality.Bars - prev.Quality.Bars
+			c.PrevCellId = prev.CellId
+			c.PrevTechnology = prev.Quality.Technology
+			c.Handover, c.Fallback = mobCompare(prev, cur)
+		}
+
+		hist = append(hist, cur)
+		if len(hist) > h.size {
+			hist = append([]*MobSignalSample(nil), hist[len(hist)-h.size:]...)
+		}
+		h.hist[k] = hist
+	}
+
+	return out, nil
+}
+
+// Returns signal history of modem from oldest to newest sample
+func (h *MobSignalHistory) History(dev, modem string) []*MobSignalSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]*MobSignalSample(nil), h.hist[mobHistKey(dev, modem)]...)
+}
+
+// Returns number of handovers and technology fallbacks in signal history of modem
+func (h *MobSignalHistory) Events(dev, modem string) (int, int) {
+	var handovers, fallbacks int
+
+	hist := h.History(dev, modem)
+	for i := 1; i < len(hist); i++ {
+		ho, fb := mobCompare(hist[i-1], hist[i])
+		if ho {
+			handovers++
+		}
+		if fb {
+			fallbacks++
+		}
+	}
+
+	return handovers, fallbacks
+}
+
+// Remove device state
+func (h *MobSignalHistory) Forget(dev string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for k := range h.hist {
+		if strings.HasPrefix(k, dev+"/") {
+			delete(h.hist, k)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package godevman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMobTechnology(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"LTE", MobTechLte},
+		{"E-UTRAN", MobTechLte},
+		{"eutran", MobTechLte},
+		{"LTE-A", MobTechLte},
+		{"4G", MobTechLte},
+		{"5G-NSA", MobTechNr},
+		{"NR5G", MobTechNr},
+		{"UTRAN", MobTechUmts},
+		{"WCDMA", MobTechUmts},
+		{"HSPA+", MobTechUmts},
+		{"3G", MobTechUmts},
+		{"GSM", MobTechGsm},
+		{"EDGE", MobTechGsm},
+		{"GPRS", MobTechGsm},
+		{"", MobTechUnknown},
+		{"unknown", MobTechUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := MobTechnology(tt.in); got != tt.want {
+			t.Errorf("MobTechnology(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMobClassify(t *testing.T) {
+	dbm := func(v int64) SensorVal { return signedSensorVal(v, "dBm", 1) }
+	tech := func(s string) SensorVal { return SensorVal{String: s, IsSet: true} }
+
+	tests := []struct {
+		name   string
+		in     MobSignal
+		tech   string
+		bars   int
+		class  string
+		metric string
+	}{
+		{
+			name:   "lte by rsrp",
+			in:     MobSignal{Technology: tech("LTE"), Rsrp: dbm(-85), Sinr: dbm(25)},
+			tech:   MobTechLte,
+			bars:   4,
+			class:  "good",
+			metric: "rsrp",
+		},
+		{
+			name:   "lte limited by sinr",
+			in:     MobSignal{Technology: tech("E-UTRAN"), Rsrp: dbm(-75), Sinr: dbm(2)},
+			tech:   MobTechLte,
+			bars:   2,
+			class:  "poor",
+			metric: "sinr",
+		},
+		{
+			name:   "lte without rsrp uses rssi",
+			in:     MobSignal{Technology: tech("LTE"), Rssi: dbm(-70)},
+			tech:   MobTechLte,
+			bars:   4,
+			class:  "good",
+			metric: "rssi",
+		},
+		{
+			name:   "umts by rssi",
+			in:     MobSignal{Technology: tech("WCDMA"), Rssi: dbm(-97)},
+			tech:   MobTechUmts,
+			bars:   2,
+			class:  "poor",
+			metric: "rssi",
+		},
+		{
+			name:   "gsm by signal in dBm",
+			in:     MobSignal{Technology: tech("GSM"), Signal: dbm(-65)},
+			tech:   MobTechGsm,
+			bars:   5,
+			class:  "excellent",
+			metric: "rssi",
+		},
+		{
+			name:   "device bars",
+			in:     MobSignal{Technology: tech("LTE"), SignalBars: SensorVal{Value: 3, Divisor: 1, IsSet: true}},
+			tech:   MobTechLte,
+			bars:   4,
+			class:  "good",
+			metric: "bars",
+		},
+		{
+			name:   "no signal",
+			in:     MobSignal{Technology: tech("LTE"), Rsrp: dbm(-130)},
+			tech:   MobTechLte,
+			bars:   0,
+			class:  "no signal",
+			metric: "rsrp",
+		},
+		{
+			name:   "no readings",
+			in:     MobSignal{},
+			tech:   MobTechUnknown,
+			bars:   0,
+			class:  "no signal",
+			metric: "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := MobClassify(tt.in)
+			if q.Technology != tt.tech || q.Bars != tt.bars || q.Class != tt.class || q.Metric != tt.metric {
+				t.Errorf("MobClassify() = %+v, want {Technology:%s Class:%s Bars:%d Metric:%s}",
+					*q, tt.tech, tt.class, tt.bars, tt.metric)
+			}
+		})
+	}
+}
+
+func TestMobSignalHistory(t *testing.T) {
+	sig := func(tech, cell string, rsrp int64) map[string]MobSignal {
+		return map[string]MobSignal{"1": {
+			Technology: SensorVal{String: tech, IsSet: true},
+			CellId:     SensorVal{String: cell, IsSet: true},
+			Rsrp:       signedSensorVal(rsrp, "dBm", 1),
+			Rssi:       signedSensorVal(rsrp, "dBm", 1),
+		}}
+	}
+
+	h := NewMobSignalHistory(0)
+	now := time.Now()
+
+	c, err := h.Update("10.0.0.1", now, sig("LTE", "A", -85))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["1"].Handover || c["1"].Fallback || c["1"].PrevTechnology != "" {
+		t.Errorf("first sample change = %+v, want no previous values", *c["1"])
+	}
+
+	c, _ = h.Update("10.0.0.1", now.Add(time.Minute), sig("WCDMA", "B", -97))
+	if !c["1"].Handover || !c["1"].Fallback || c["1"].BarsDelta != -2 || c["1"].PrevCellId != "A" {
+		t.Errorf("second sample change = %+v, want handover, fallback and -2 bars", *c["1"])
+	}
+
+	if ho, fb := h.Events("10.0.0.1", "1"); ho != 1 || fb != 1 {
+		t.Errorf("Events() = %d, %d, want 1, 1", ho, fb)
+	}
+
+	h.Forget("10.0.0.1")
+	if l := len(h.History("10.0.0.1", "1")); l != 0 {
+		t.Errorf("history length after Forget = %d, want 0", l)
+	}
+}
@@ -0,0 +1,228 @@
+package godevman
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PON optical budget used by ONU health analysis
+type PonBudget struct {
+	// Allowed ONU receive power range (dBm)
+	RxMin, RxMax float64
+	// Allowed ONU transmit power range (dBm)
+	TxMin, TxMax float64
+	// Max downstream path loss (OLT PON port TX - ONU RX) (dB)
+	MaxPathLoss float64
+	// Max ONU laser bias current (mA). 0 - not checked
+	MaxLaserBias float64
+	// Max ONU distance (m). 0 - not checked
+	MaxDistance float64
+	// ONU RX power drop between two polls considered as sudden degradation (dB)
+	DegradeDb float64
+}
+
+// Returns GPON class B+ optical budget
+func DefaultPonBudget() *PonBudget {
+	return &PonBudget{
+		RxMin:       -27,
+		RxMax:       -8,
+		TxMin:       0.5,
+		TxMax:       5,
+		MaxPathLoss: 28,
+		MaxDistance: 20000,
+		DegradeDb:   3,
+	}
+}
+
+// ONU optical health
+type PonOnuHealth struct {
+	OltPort, Name               string
+	RxPower, TxPower, LaserBias ValF64
+	// Downstream path loss calculated from OLT PON port TX power (dB)
+	PathLoss ValF64
+	// ONU RX power change since previous poll (dB)
+	RxDelta ValF64
+	// Detected problems. Empty if ONU is healthy
+	Issues []string
+}
+
+// PON port statistics
+type PonPortStats struct {
+	// Number of ONUs, online ONUs and ONUs with detected problems
+	Onus, Online, Faulty int
+	// Lowest ONU RX power (dBm) and serial of ONU
+	WorstRx    ValF64
+	WorstRxOnu string
+	// Average distance of online ONUs (m)
+	AvgDistance ValF64
+	// OLT PON port SFP readings (dBm)
+	OltTxPower, OltRxPower ValF64
+	// Detected PON port problems
+	Issues []string
+}
+
+// PON optical health analysis result
+type PonHealth struct {
+	// Map keys are ONU serials
+	Onus map[string]*PonOnuHealth
+	// Map keys are OLT PON ports
+	Ports map[string]*PonPortStats
+}
+
+// Returns serials of ONUs with detected problems sorted by OLT port and serial
+func (h *PonHealth) Faulty() []string {
+	var out []string
+	for s, o := range h.Onus {
+		if len(o.Issues) > 0 {
+			out = append(out, s)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := h.Onus[out[i]], h.Onus[out[j]]
+		if a.OltPort != b.OltPort {
+			return a.OltPort < b.OltPort
+		}
+		return out[i] < out[j]
+	})
+
+	return out
+}
+
+// Analyze PON optical health.
+// onus - current ONU info (DevOnusReader), prev - ONU info of previous poll (can be nil),
+// optics - OLT PON port info (DevPonOpticsReader, can be nil), b - optical budget (DefaultPonBudget if nil)
+func PonHealthCheck(onus, prev map[string]*OnuInfo, optics map[string]*PonOptics, b *PonBudget) *PonHealth {
+	if b == nil {
+		b = DefaultPonBudget()
+	}
+
+	out := &PonHealth{
+		Onus:  make(map[string]*PonOnuHealth),
+		Ports: make(map[string]*PonPortStats),
+	}
+
+	dist := make(map[string][]float64)
+
+	for port, o := range optics {
+		ps := new(PonPortStats)
+		out.Ports[port] = ps
+
+		if v, ok := sensorFloat(o.TxPower); ok {
+			ps.OltTxPower = ValF64{Value: v, IsSet: true}
+		}
+		if v, ok := sensorFloat(o.RxPower); ok {
+			ps.OltRxPower = ValF64{Value: v, IsSet: true}
+		}
+		if o.Present.IsSet && !o.Present.Value {
+			ps.Issues = append(ps.Issues, "sfp not present")
+		}
+		if o.Los.IsSet && o.Los.Value {
+			ps.Issues = append(ps.Issues, "sfp loss of signal")
+		}
+	}
+
+	for serial, o := range onus {
+		h := &PonOnuHealth{
+			OltPort: o.OltPort.Value,
+			Name:    o.Name.Value,
+		}
+		out.Onus[serial] = h
+
+		ps, ok := out.Ports[h.OltPort]
+		if !ok {
+			ps = new(PonPortStats)
+			out.Ports[h.OltPort] = ps
+		}
+		ps.Onus++
+
+		var p *OnuInfo
+		if prev != nil {
+			p = prev[serial]
+		}
+
+		if o.Enabled.IsSet && !o.Enabled.Value {
+			continue
+		}
+
+		if o.Online.IsSet && !o.Online.Value {
+			if p != nil && p.Online.IsSet && p.Online.Value {
+				h.Issues = append(h.Issues, "went offline since previous poll")
+			} else {
+				h.Issues = append(h.Issues, "offline")
+			}
+			ps.Faulty++
+			continue
+		}
+		ps.Online++
+
+		if v, ok := sensorFloat(o.RxPower); ok {
+			h.RxPower = ValF64{Value: v, IsSet: true}
+			switch {
+			case v < b.RxMin:
+				h.Issues = append(h.Issues, fmt.Sprintf("rx power %.2f dBm is below %.2f dBm", v, b.RxMin))
+			case v > b.RxMax:
+				h.Issues = append(h.Issues, fmt.Sprintf("rx power %.2f dBm is above %.2f dBm", v, b.RxMax))
+			}
+
+			if !ps.WorstRx.IsSet || v < ps.WorstRx.Value {
+				ps.WorstRx = h.RxPower
+				ps.WorstRxOnu = serial
+			}
+
+			if ps.OltTxPower.IsSet {
+				h.PathLoss = ValF64{Value: ps.OltTxPower.Value - v, IsSet: true}
+				if h.PathLoss.Value > b.MaxPathLoss {
+					h.Issues = append(h.Issues, fmt.Sprintf("path loss %.2f dB exceeds %.2f dB", h.PathLoss.Value, b.MaxPathLoss))
+				}
+			}
+
+			if p != nil {
+				if pv, ok := sensorFloat(p.RxPower); ok {
+					h.RxDelta = ValF64{Value: v - pv, IsSet: true}
+					if pv-v >= b.DegradeDb {
+						h.Issues = append(h.Issues, fmt.Sprintf("rx power dropped %.2f dB since previous poll", pv-v))
+					}
+				}
+			}
+		}
+
+		if v, ok := sensorFloat(o.TxPower); ok {
+			h.TxPower = ValF64{Value: v, IsSet: true}
+			switch {
+			case v < b.TxMin:
+				h.Issues = append(h.Issues, fmt.Sprintf("tx power %.2f dBm is below %.2f dBm", v, b.TxMin))
+			case v > b.TxMax:
+				h.Issues = append(h.Issues, fmt.Sprintf("tx power %.2f dBm is above %.2f dBm", v, b.TxMax))
+			}
+		}
+
+		if v, ok := sensorFloat(o.LaserBias); ok {
+			h.LaserBias = ValF64{Value: v, IsSet: true}
+			if b.MaxLaserBias > 0 && v > b.MaxLaserBias {
+				h.Issues = append(h.Issues, fmt.Sprintf("laser bias %.2f mA exceeds %.2f mA", v, b.MaxLaserBias))
+			}
+		}
+
+		if v, ok := sensorFloat(o.Distance); ok {
+			dist[h.OltPort] = append(dist[h.OltPort], v)
+			if b.MaxDistance > 0 && v > b.MaxDistance {
+				h.Issues = append(h.Issues, fmt.Sprintf("distance %.0f m exceeds %.0f m", v, b.MaxDistance))
+			}
+		}
+
+		if len(h.Issues) > 0 {
+			ps.Faulty++
+		}
+	}
+
+	for port, d := range dist {
+		var sum float64
+		for _, v := range d {
+			sum += v
+		}
+		out.Ports[port].AvgDistance = ValF64{Value: sum / float64(len(d)), IsSet: true}
+	}
+
+	return out
+}
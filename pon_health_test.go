@@ -0,0 +1,114 @@
+package godevman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPonHealthCheck(t *testing.T) {
+	// dBm value with 0.01 resolution
+	dbm := func(v int64) SensorVal { return signedSensorVal(v, "dBm", 100) }
+	onu := func(port string, online bool, rx, tx int64) *OnuInfo {
+		return &OnuInfo{
+			OltPort: ValString{Value: port, IsSet: true},
+			Online:  ValBool{Value: online, IsSet: true},
+			Enabled: ValBool{Value: true, IsSet: true},
+			RxPower: dbm(rx),
+			TxPower: dbm(tx),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		onu    *OnuInfo
+		prev   *OnuInfo
+		optics *PonOptics
+		issues []string
+		faulty int
+	}{
+		{
+			name: "healthy",
+			onu:  onu("0/1", true, -2000, 200),
+		},
+		{
+			name:   "low rx power",
+			onu:    onu("0/1", true, -2850, 200),
+			issues: []string{"rx power -28.50 dBm is below -27.00 dBm"},
+			faulty: 1,
+		},
+		{
+			name:   "high tx power",
+			onu:    onu("0/1", true, -2000, 600),
+			issues: []string{"tx power 6.00 dBm is above 5.00 dBm"},
+			faulty: 1,
+		},
+		{
+			name:   "offline",
+			onu:    onu("0/1", false, 0, 0),
+			issues: []string{"offline"},
+			faulty: 1,
+		},
+		{
+			name:   "went offline",
+			onu:    onu("0/1", false, 0, 0),
+			prev:   onu("0/1", true, -2000, 200),
+			issues: []string{"went offline since previous poll"},
+			faulty: 1,
+		},
+		{
+			name:   "rx power drop",
+			onu:    onu("0/1", true, -2400, 200),
+			prev:   onu("0/1", true, -2000, 200),
+			issues: []string{"rx power dropped 4.00 dB since previous poll"},
+			faulty: 1,
+		},
+		{
+			name:   "path loss",
+			onu:    onu("0/1", true, -2600, 200),
+			optics: &PonOptics{TxPower: dbm(300)},
+			issues: []string{"path loss 29.00 dB exceeds 28.00 dB"},
+			faulty: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onus := map[string]*OnuInfo{"HWTC00000001": tt.onu}
+			var prev map[string]*OnuInfo
+			if tt.prev != nil {
+				prev = map[string]*OnuInfo{"HWTC00000001": tt.prev}
+			}
+			var optics map[string]*PonOptics
+			if tt.optics != nil {
+				optics = map[string]*PonOptics{"0/1": tt.optics}
+			}
+
+			h := PonHealthCheck(onus, prev, optics, nil)
+
+			if got := h.Onus["HWTC00000001"].Issues; !reflect.DeepEqual(got, tt.issues) {
+				t.Errorf("issues = %q, want %q", got, tt.issues)
+			}
+			if got := h.Ports["0/1"].Faulty; got != tt.faulty {
+				t.Errorf("faulty = %d, want %d", got, tt.faulty)
+			}
+			if got := h.Ports["0/1"].Onus; got != 1 {
+				t.Errorf("onus = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestPonHealthFaulty(t *testing.T) {
+	onus := map[string]*OnuInfo{
+		"B": {OltPort: ValString{Value: "0/1", IsSet: true}, Online: ValBool{IsSet: true}},
+		"A": {OltPort: ValString{Value: "0/2", IsSet: true}, Online: ValBool{IsSet: true}},
+		"C": {OltPort: ValString{Value: "0/1", IsSet: true}, Online: ValBool{IsSet: true}},
+		"D": {OltPort: ValString{Value: "0/1", IsSet: true}, Online: ValBool{Value: true, IsSet: true}},
+	}
+
+	got := PonHealthCheck(onus, nil, nil, nil).Faulty()
+	want := []string{"B", "C", "A"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Faulty() = %q, want %q", got, want)
+	}
+}
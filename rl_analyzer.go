@@ -0,0 +1,417 @@
+package godevman
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Radio link analysis thresholds
+type RlThresholds struct {
+	// Receiver threshold (dBm) used for fade margin calculation
+	RxThreshold float64
+	// Min allowed fade margin (dB)
+	MinFadeMargin float64
+	// Max allowed path loss (dB). 0 - not checked
+	MaxPathLoss float64
+	// Max allowed path loss difference between directions (dB)
+	MaxAsymmetry float64
+	// Fade margin decrease from average of previous polls considered as degradation (dB)
+	DegradeDb float64
+}
+
+// Returns default radio link analysis thresholds
+func DefaultRlThresholds() *RlThresholds {
+	return &RlThresholds{
+		RxThreshold:   -70,
+		MinFadeMargin: 15,
+		MaxAsymmetry:  3,
+		DegradeDb:     3,
+	}
+}
+
+// Radio link readings of devices
+type RlSnapshot struct {
+	Time time.Time
+	// Radio info (DevRlReader.RlInfo). Map keys are device ips
+	Info map[string]map[string]*RlRadioIfInfo
+	// Far end info (DevRlReader.RlNbrInfo). Map keys are device ips
+	Nbr map[string]map[string]*RlRadioFeIfInfo
+}
+
+// Poll radio link info of devices. devs - map keys are device ips.
+// Returns readings of successfully polled devices and error if polling of some devices failed.
+func PollRl(devs map[string]DevRlReader) (*RlSnapshot, error) {
+	s := &RlSnapshot{
+		Time: time.Now(),
+		Info: make(map[string]map[string]*RlRadioIfInfo),
+		Nbr:  make(map[string]map[string]*RlRadioFeIfInfo),
+	}
+
+	var errs []string
+	for ip, d := range devs {
+		info, err := d.RlInfo()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ip, err))
+			continue
+		}
+
+		nbr, err := d.RlNbrInfo()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ip, err))
+			continue
+		}
+
+		s.Info[ip] = info
+		s.Nbr[ip] = nbr
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return s, fmt.Errorf("radio link poll failed: %s", strings.Join(errs, "; "))
+	}
+
+	return s, nil
+}
+
+// Radio link hop end
+type RlHopEnd struct {
+	// Device ip and radio ifdescription
+	Dev, Radio string
+	// Readings are taken from far end info of other end if device is not polled
+	FromNbr bool
+	// canonical end identifier
+	key string
+}
+
+// Returns canonical hop end identifier. Radio ifIndex is preferred over radio ifdescription
+func rlEndKey(dev string, idx ValInt, radio string) string {
+	if idx.IsSet {
+		return dev + ":" + strconv.Itoa(idx.Value)
+	}
+	return dev + ":" + radio
+}
+
+// Returns hop end identifier
+func (e *RlHopEnd) id() string {
+	if e.key != "" {
+		return e.key
+	}
+	return e.Dev + ":" + e.Radio
+}
+
+// Radio link hop direction
+type RlPath struct {
+	// Transmitter output power and receiver input power (dBm)
+	PowerOut, PowerIn ValF64
+	// Path loss and fade margin (dB)
+	PathLoss, FadeMargin ValF64
+	// Fade margin decreased compared to previous polls
+	Degraded bool
+}
+
+// Radio link hop
+type RlHop struct {
+	A, B RlHopEnd
+	// Directions A -> B and B -> A
+	AtoB, BtoA RlPath
+	// Path loss difference between directions (dB)
+	Asymmetry ValF64
+	// Detected problems. Empty if hop is healthy
+	Issues []string
+}
+
+// Returns hop id. Id does not depend on hop end order or on whether far end device was polled
+func (h *RlHop) Id() string {
+	a, b := h.A.id(), h.B.id()
+	if b < a {
+		a, b = b, a
+	}
+
+	return a + "<>" + b
+}
+
+// Radio link analysis result
+type RlLinks struct {
+	Hops []*RlHop
+	// Radios without identified far end ("<device ip>:<radio>")
+	Unpaired []string
+}
+
+// Returns first RF of radio which has power readings
+func rlRf(i *RlRadioIfInfo) *RfInfo {
+	rauKeys := make([]string, 0, len(i.Rau))
+	for k := range i.Rau {
+		rauKeys = append(rauKeys, k)
+	}
+	sort.Strings(rauKeys)
+
+	for _, rk := range rauKeys {
+		rau := i.Rau[rk]
+		rfKeys := make([]string, 0, len(rau.Rf))
+		for k := range rau.Rf {
+			rfKeys = append(rfKeys, k)
+		}
+		sort.Strings(rfKeys)
+
+		for _, fk := range rfKeys {
+			if rf := rau.Rf[fk]; rf.PowerIn.IsSet || rf.PowerOut.IsSet {
+				return rf
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns far end info of radio. Single far end entry is used for radio of PtP device
+func rlNbr(s *RlSnapshot, dev, radio string) *RlRadioFeIfInfo {
+	nbr := s.Nbr[dev]
+	if fe, ok := nbr[radio]; ok {
+		return fe
+	}
+	if len(nbr) == 1 && len(s.Info[dev]) == 1 {
+		for _, fe := range nbr {
+			return fe
+		}
+	}
+
+	return nil
+}
+
+// Returns radio of far end device which is connected to radio of near end device
+func rlFarRadio(s *RlSnapshot, near string, fe *RlRadioFeIfInfo) string {
+	far := fe.Ip.Value
+
+	radios := make([]string, 0, len(s.Info[far]))
+	for r := range s.Info[far] {
+		radios = append(radios, r)
+	}
+	sort.Strings(radios)
+
+	for _, r := range radios {
+		i := s.Info[far][r]
+		if fe.FeIfIdx.IsSet && i.IfIdx.IsSet && fe.FeIfIdx.Value == i.IfIdx.Value {
+			return r
+		}
+		if fe.FeIfDescr.IsSet && fe.FeIfDescr.Value == r {
+			return r
+		}
+	}
+
+	// Radio which sees near end device as its far end
+	for _, r := range radios {
+		if b := rlNbr(s, far, r); b != nil && b.Ip.Value == near {
+			return r
+		}
+	}
+
+	return ""
+}
+
+// Set path readings
+func (p *RlPath) set(out, in ValF64, th *RlThresholds) {
+	p.PowerOut, p.PowerIn = out, in
+	if out.IsSet && in.IsSet {
+		p.PathLoss = ValF64{Value: out.Value - in.Value, IsSet: true}
+	}
+	if in.IsSet {
+		p.FadeMargin = ValF64{Value: in.Value - th.RxThreshold, IsSet: true}
+	}
+}
+
+// Pair radio link ends and analyze hops. th - thresholds (DefaultRlThresholds if nil)
+func RlHops(s *RlSnapshot, th *RlThresholds) *RlLinks {
+	if th == nil {
+		th = DefaultRlThresholds()
+	}
+
+	out := new(RlLinks)
+	seen := make(map[string]bool)
+
+	devs := make([]string, 0, len(s.Info))
+	for d := range s.Info {
+		devs = append(devs, d)
+	}
+	sort.Strings(devs)
+
+	for _, dev := range devs {
+		radios := make([]string, 0, len(s.Info[dev]))
+		for r := range s.Info[dev] {
+			radios = append(radios, r)
+		}
+		sort.Strings(radios)
+
+		for _, radio := range radios {
+			if seen[dev+":"+radio] {
+				continue
+			}
+
+			fe := rlNbr(s, dev, radio)
+			if fe == nil || !fe.Ip.IsSet || fe.Ip.Value == "" {
+				out.Unpaired = append(out.Unpaired, dev+":"+radio)
+				continue
+			}
+
+			h := &RlHop{A: RlHopEnd{Dev: dev, Radio: radio, key: rlEndKey(dev, s.Info[dev][radio].IfIdx, radio)}}
+			seen[dev+":"+radio] = true
+
+			var aOut, aIn, bOut, bIn ValF64
+			if rf := rlRf(s.Info[dev][radio]); rf != nil {
+				aOut, aIn = rf.PowerOut, rf.PowerIn
+			}
+
+			if _, ok := s.Info[fe.Ip.Value]; ok {
+				far := rlFarRadio(s, dev, fe)
+				if far == "" {
+					out.Unpaired = append(out.Unpaired, dev+":"+radio)
+					continue
+				}
+				seen[fe.Ip.Value+":"+far] = true
+
+				h.B = RlHopEnd{Dev: fe.Ip.Value, Radio: far, key: rlEndKey(fe.Ip.Value, s.Info[fe.Ip.Value][far].IfIdx, far)}
+				if rf := rlRf(s.Info[fe.Ip.Value][far]); rf != nil {
+					bOut, bIn = rf.PowerOut, rf.PowerIn
+				}
+			} else {
+				// Far end device is not polled
+				h.B = RlHopEnd{
+					Dev:     fe.Ip.Value,
+					Radio:   fe.FeIfDescr.Value,
+					FromNbr: true,
+					key:     rlEndKey(fe.Ip.Value, fe.FeIfIdx, fe.FeIfDescr.Value),
+				}
+				bOut, bIn = fe.PowerOut, fe.PowerIn
+			}
+
+			h.AtoB.set(aOut, bIn, th)
+			h.BtoA.set(bOut, aIn, th)
+			rlHopCheck(h, th)
+
+			out.Hops = append(out.Hops, h)
+		}
+	}
+
+	return out
+}
+
+// Check hop readings against thresholds
+func rlHopCheck(h *RlHop, th *RlThresholds) {
+	paths := []struct {
+		name string
+		p    *RlPath
+	}{
+		{h.A.Dev + " -> " + h.B.Dev, &h.AtoB},
+		{h.B.Dev + " -> " + h.A.Dev, &h.BtoA},
+	}
+
+	for _, p := range paths {
+		if !p.p.PowerIn.IsSet {
+			h.Issues = append(h.Issues, fmt.Sprintf("%s: no receive level", p.name))
+			continue
+		}
+		if p.p.FadeMargin.Value < th.MinFadeMargin {
+			h.Issues = append(h.Issues, fmt.Sprintf("%s: fade margin %.1f dB is below %.1f dB",
+				p.name, p.p.FadeMargin.Value, th.MinFadeMargin))
+		}
+		if th.MaxPathLoss > 0 && p.p.PathLoss.IsSet && p.p.PathLoss.Value > th.MaxPathLoss {
+			h.Issues = append(h.Issues, fmt.Sprintf("%s: path loss %.1f dB exceeds %.1f dB",
+				p.name, p.p.PathLoss.Value, th.MaxPathLoss))
+		}
+	}
+
+	if h.AtoB.PathLoss.IsSet && h.BtoA.PathLoss.IsSet {
+		h.Asymmetry = ValF64{Value: math.Abs(h.AtoB.PathLoss.Value - h.BtoA.PathLoss.Value), IsSet: true}
+		if h.Asymmetry.Value > th.MaxAsymmetry {
+			h.Issues = append(h.Issues, fmt.Sprintf("asymmetric path loss: %.1f dB difference between directions",
+				h.Asymmetry.Value))
+		}
+	}
+}
+
+// Stateful radio link analyzer. Keeps fade margin history per hop direction for degradation detection.
+type RlAnalyzer struct {
+	mu   sync.Mutex
+	th   *RlThresholds
+	size int
+	// fade margin history. Keys are hop id and receiving end id
+	hist map[string]map[string][]float64
+}
+
+// Initialize new radio link analyzer.
+// th - thresholds (DefaultRlThresholds if nil), size - number of polls kept per hop (default 24)
+func NewRlAnalyzer(th *RlThresholds, size int) *RlAnalyzer {
+	if th == nil {
+		th = DefaultRlThresholds()
+	}
+	if size <= 0 {
+		size = 24
+	}
+
+	return &RlAnalyzer{
+		th:   th,
+		size: size,
+		hist: make(map[string]map[string][]float64),
+	}
+}
+
+// Analyze radio link readings and compare fade margins with average of previous polls
+func (a *RlAnalyzer) Analyze(s *RlSnapshot) *RlLinks {
+	out := RlHops(s, a.th)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, h := range out.Hops {
+		id := h.Id()
+		if a.hist[id] == nil {
+			a.hist[id] = make(map[string][]float64)
+		}
+
+		for _, d := range []struct {
+			from, to *RlHopEnd
+			p        *RlPath
+		}{{&h.A, &h.B, &h.AtoB}, {&h.B, &h.A, &h.BtoA}} {
+			p := d.p
+			if !p.FadeMargin.IsSet {
+				continue
+			}
+
+			k := d.to.id()
+			hist := a.hist[id][k]
+			if len(hist) > 0 {
+				var sum float64
+				for _, v := range hist {
+					sum += v
+				}
+				avg := sum / float64(len(hist))
+
+				if avg-p.FadeMargin.Value >= a.th.DegradeDb {
+					p.Degraded = true
+					h.Issues = append(h.Issues, fmt.Sprintf("%s -> %s: fade margin decreased %.1f dB from average",
+						d.from.Dev, d.to.Dev, avg-p.FadeMargin.Value))
+				}
+			}
+
+			hist = append(hist, p.FadeMargin.Value)
+			if len(hist) > a.size {
+				hist = append([]float64(nil), hist[len(hist)-a.size:]...)
+			}
+			a.hist[id][k] = hist
+		}
+	}
+
+	return out
+}
+
+// Remove hop history
+func (a *RlAnalyzer) Forget(hopId string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.hist, hopId)
+}
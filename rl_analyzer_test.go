@@ -0,0 +1,188 @@
+package godevman
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Returns radio info with single RF
+func testRlRadio(idx int, out, in float64) *RlRadioIfInfo {
+	return &RlRadioIfInfo{
+		IfIdx: ValInt{Value: idx, IsSet: true},
+		Rau: map[string]*RauInfo{"1": {Rf: map[string]*RfInfo{"1": {
+			PowerOut: ValF64{Value: out, IsSet: true},
+			PowerIn:  ValF64{Value: in, IsSet: true},
+		}}}},
+	}
+}
+
+// Returns far end info
+func testRlNbr(ip string, feIdx int, out, in float64) *RlRadioFeIfInfo {
+	return &RlRadioFeIfInfo{
+		Ip:       ValString{Value: ip, IsSet: true},
+		FeIfIdx:  ValInt{Value: feIdx, IsSet: true},
+		PowerOut: ValF64{Value: out, IsSet: true},
+		PowerIn:  ValF64{Value: in, IsSet: true},
+	}
+}
+
+// Returns snapshot of hop 10.0.0.1 (RL1, ifIndex 5) <> 10.0.0.2 (RL2, ifIndex 7).
+// polled - polled device ips, in1 and in2 - receive levels of devices
+func testRlSnapshot(polled []string, in1, in2 float64) *RlSnapshot {
+	s := &RlSnapshot{
+		Info: make(map[string]map[string]*RlRadioIfInfo),
+		Nbr:  make(map[string]map[string]*RlRadioFeIfInfo),
+	}
+
+	for _, ip := range polled {
+		switch ip {
+		case "10.0.0.1":
+			s.Info[ip] = map[string]*RlRadioIfInfo{"RL1": testRlRadio(5, 20, in1)}
+			s.Nbr[ip] = map[string]*RlRadioFeIfInfo{"RL1": testRlNbr("10.0.0.2", 7, 18, in2)}
+		case "10.0.0.2":
+			s.Info[ip] = map[string]*RlRadioIfInfo{"RL2": testRlRadio(7, 18, in2)}
+			s.Nbr[ip] = map[string]*RlRadioFeIfInfo{"RL2": testRlNbr("10.0.0.1", 5, 20, in1)}
+		}
+	}
+
+	return s
+}
+
+func TestRlHops(t *testing.T) {
+	tests := []struct {
+		name     string
+		polled   []string
+		a, b     RlHopEnd
+		atob     RlPath
+		btoa     RlPath
+		issues   []string
+		unpaired []string
+	}{
+		{
+			name:   "both ends polled",
+			polled: []string{"10.0.0.2", "10.0.0.1"},
+			a:      RlHopEnd{Dev: "10.0.0.1", Radio: "RL1", key: "10.0.0.1:5"},
+			b:      RlHopEnd{Dev: "10.0.0.2", Radio: "RL2", key: "10.0.0.2:7"},
+			atob: RlPath{
+				PowerOut: ValF64{Value: 20, IsSet: true}, PowerIn: ValF64{Value: -45, IsSet: true},
+				PathLoss: ValF64{Value: 65, IsSet: true}, FadeMargin: ValF64{Value: 25, IsSet: true},
+			},
+			btoa: RlPath{
+				PowerOut: ValF64{Value: 18, IsSet: true}, PowerIn: ValF64{Value: -40, IsSet: true},
+				PathLoss: ValF64{Value: 58, IsSet: true}, FadeMargin: ValF64{Value: 30, IsSet: true},
+			},
+			issues: []string{"asymmetric path loss: 7.0 dB difference between directions"},
+		},
+		{
+			name:   "far end not polled",
+			polled: []string{"10.0.0.2"},
+			a:      RlHopEnd{Dev: "10.0.0.2", Radio: "RL2", key: "10.0.0.2:7"},
+			b:      RlHopEnd{Dev: "10.0.0.1", FromNbr: true, key: "10.0.0.1:5"},
+			atob: RlPath{
+				PowerOut: ValF64{Value: 18, IsSet: true}, PowerIn: ValF64{Value: -40, IsSet: true},
+				PathLoss: ValF64{Value: 58, IsSet: true}, FadeMargin: ValF64{Value: 30, IsSet: true},
+			},
+			btoa: RlPath{
+				PowerOut: ValF64{Value: 20, IsSet: true}, PowerIn: ValF64{Value: -45, IsSet: true},
+				PathLoss: ValF64{Value: 65, IsSet: true}, FadeMargin: ValF64{Value: 25, IsSet: true},
+			},
+			issues: []string{"asymmetric path loss: 7.0 dB difference between directions"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := RlHops(testRlSnapshot(tt.polled, -40, -45), nil)
+			if len(l.Hops) != 1 {
+				t.Fatalf("hops count = %d, want 1", len(l.Hops))
+			}
+
+			h := l.Hops[0]
+			if h.A != tt.a || h.B != tt.b {
+				t.Errorf("ends = %+v, %+v, want %+v, %+v", h.A, h.B, tt.a, tt.b)
+			}
+			if h.AtoB != tt.atob || h.BtoA != tt.btoa {
+				t.Errorf("paths = %+v, %+v, want %+v, %+v", h.AtoB, h.BtoA, tt.atob, tt.btoa)
+			}
+			if !reflect.DeepEqual(h.Issues, tt.issues) {
+				t.Errorf("issues = %q, want %q", h.Issues, tt.issues)
+			}
+			if !reflect.DeepEqual(l.Unpaired, tt.unpaired) {
+				t.Errorf("unpaired = %q, want %q", l.Unpaired, tt.unpaired)
+			}
+		})
+	}
+}
+
+func TestRlHopsThresholds(t *testing.T) {
+	th := &RlThresholds{RxThreshold: -70, MinFadeMargin: 28, MaxPathLoss: 60, MaxAsymmetry: 10}
+
+	l := RlHops(testRlSnapshot([]string{"10.0.0.1", "10.0.0.2"}, -40, -45), th)
+	want := []string{
+		"10.0.0.1 -> 10.0.0.2: fade margin 25.0 dB is below 28.0 dB",
+		"10.0.0.1 -> 10.0.0.2: path loss 65.0 dB exceeds 60.0 dB",
+	}
+	if !reflect.DeepEqual(l.Hops[0].Issues, want) {
+		t.Errorf("issues = %q, want %q", l.Hops[0].Issues, want)
+	}
+}
+
+func TestRlHopsUnpaired(t *testing.T) {
+	s := testRlSnapshot([]string{"10.0.0.1"}, -40, -45)
+	s.Info["10.0.0.1"]["RL9"] = testRlRadio(9, 20, -40)
+
+	l := RlHops(s, nil)
+	if len(l.Hops) != 1 {
+		t.Errorf("hops count = %d, want 1", len(l.Hops))
+	}
+	if want := []string{"10.0.0.1:RL9"}; !reflect.DeepEqual(l.Unpaired, want) {
+		t.Errorf("unpaired = %q, want %q", l.Unpaired, want)
+	}
+}
+
+func TestRlHopId(t *testing.T) {
+	var ids []string
+	for _, polled := range [][]string{{"10.0.0.1", "10.0.0.2"}, {"10.0.0.1"}, {"10.0.0.2"}} {
+		l := RlHops(testRlSnapshot(polled, -40, -45), nil)
+		if len(l.Hops) != 1 {
+			t.Fatalf("polled %q: hops count = %d, want 1", polled, len(l.Hops))
+		}
+		ids = append(ids, l.Hops[0].Id())
+	}
+
+	want := "10.0.0.1:5<>10.0.0.2:7"
+	for i, id := range ids {
+		if id != want {
+			t.Errorf("id %d = %q, want %q", i, id, want)
+		}
+	}
+}
+
+func TestRlAnalyzerAnalyze(t *testing.T) {
+	a := NewRlAnalyzer(nil, 2)
+
+	// History is kept across polls with different polled ends
+	for _, polled := range [][]string{{"10.0.0.1", "10.0.0.2"}, {"10.0.0.1"}} {
+		l := a.Analyze(testRlSnapshot(polled, -40, -45))
+		if h := l.Hops[0]; h.AtoB.Degraded || h.BtoA.Degraded {
+			t.Fatalf("polled %q: unexpected degradation: %q", polled, h.Issues)
+		}
+	}
+
+	// Receive level of 10.0.0.1 drops 5 dB
+	l := a.Analyze(testRlSnapshot([]string{"10.0.0.2"}, -45, -45))
+	h := l.Hops[0]
+	if !h.AtoB.Degraded || h.BtoA.Degraded {
+		t.Errorf("degraded = %t, %t, want true, false", h.AtoB.Degraded, h.BtoA.Degraded)
+	}
+	if want := "10.0.0.2 -> 10.0.0.1: fade margin decreased 5.0 dB from average"; h.Issues[len(h.Issues)-1] != want {
+		t.Errorf("issues = %q, want last %q", h.Issues, want)
+	}
+
+	// History is removed
+	a.Forget(h.Id())
+	l = a.Analyze(testRlSnapshot([]string{"10.0.0.2"}, -50, -45))
+	if l.Hops[0].AtoB.Degraded {
+		t.Errorf("degraded after Forget, issues: %q", l.Hops[0].Issues)
+	}
+}
@@ -0,0 +1,342 @@
+package godevman
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Process wide pool of cli and web sessions.
+// Device objects created with Dparams.Pooled set share cli and web sessions
+// with other pooled device objects of same ip and credentials.
+// Commands on shared cli session are serialized. Number of concurrent cli and
+// web sessions to one device is limited separately according to device type.
+type SessPool struct {
+	mu sync.Mutex
+	// shared cli sessions. Key is ip and credentials
+	cli map[string]*cliSess
+	// shared web sessions. Key is ip and credentials
+	web map[string]*webSess
+	// cli session slots of device. Key is ip
+	cliSlots map[string]chan struct{}
+	// web session slots of device. Key is ip
+	webSlots map[string]chan struct{}
+	// max concurrent sessions per device type. Key is sysObjectId or it's prefix
+	limits map[string]int
+	// max wait time for free session slot
+	wait time.Duration
+}
+
+var sessPool = &SessPool{
+	cli:      make(map[string]*cliSess),
+	web:      make(map[string]*webSess),
+	cliSlots: make(map[string]chan struct{}),
+	webSlots: make(map[string]chan struct{}),
+	limits: map[string]int{
+		// Ubiquiti UFiber OLT
+		".1.3.6.1.4.1.41112.1.5": 2,
+		// Ericsson MINI-LINK PT
+		".1.3.6.1.4.1.193.223.2.1": 1,
+	},
+	wait: 60 * time.Second,
+}
+
+// Get process wide session pool
+func SessionPool() *SessPool {
+	return sessPool
+}
+
+// Set max concurrent sessions count for device type. Cli and web sessions are counted separately.
+// t - sysObjectId or it's prefix (longest match wins), n - max sessions (0 - unlimited).
+// Affects devices which are not yet in pool.
+func (p *SessPool) SetLimit(t string, n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.limits[t] = n
+}
+
+// Set max wait time for free session slot. Default is 60s
+func (p *SessPool) SetWait(w time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.wait = w
+}
+
+// Session pool statistics. Keys are device ip-s, values count of open sessions
+func (p *SessPool) Stats() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make(map[string]int)
+	for ip, s := range p.cliSlots {
+		out[ip] += len(s)
+	}
+	for ip, s := range p.webSlots {
+		out[ip] += len(s)
+	}
+
+	return out
+}
+
+// Get session slots of device from slots map. Returns nil if count of sessions is not limited
+func (p *SessPool) deviceSlots(slots map[string]chan struct{}, ip, sysObjectId string) chan struct{} {
+	if s, ok := slots[ip]; ok {
+		return s
+	}
+
+	var limit, mLen int
+	for t, n := range p.limits {
+		if strings.HasPrefix(sysObjectId, t) && len(t) > mLen {
+			limit = n
+			mLen = len(t)
+		}
+	}
+
+	var s chan struct{}
+	if limit > 0 {
+		s = make(chan struct{}, limit)
+	}
+	slots[ip] = s
+
+	return s
+}
+
+// Get shared cli session of device
+func (p *SessPool) cliSess(d *device, params *CliParams) *cliSess {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := fmt.Sprintf("%s|%s|%t|%s", d.ip, params.Port, params.Telnet, strings.Join(params.Cred, "|"))
+	if s, ok := p.cli[key]; ok {
+		return s
+	}
+
+	s := &cliSess{
+		params: params,
+		slots:  p.deviceSlots(p.cliSlots, d.ip, d.sysObjectId),
+		wait:   p.wait,
+		lease:  make(chan struct{}, 1),
+	}
+	p.cli[key] = s
+
+	return s
+}
+
+// Get shared web session of device
+func (p *SessPool) webSess(d *device, cred []string) *webSess {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := fmt.Sprintf("%s|%s", d.ip, strings.Join(cred, "|"))
+	if s, ok := p.web[key]; ok {
+		return s
+	}
+
+	s := &webSess{
+		cred:   cred,
+		pooled: true,
+		slots:  p.deviceSlots(p.webSlots, d.ip, d.sysObjectId),
+		wait:   p.wait,
+	}
+	p.web[key] = s
+
+	return s
+}
+
+// Take session slot. Blocks until free slot is available or wait time is over
+func takeSlot(slots chan struct{}, wait time.Duration) error {
+	if slots == nil {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-t.C:
+		return fmt.Errorf("no free session slot after %v", wait)
+	}
+}
+
+// Free session slot
+func freeSlot(slots chan struct{}) {
+	if slots == nil {
+		return
+	}
+	<-slots
+}
+
+// Lock cli session for exclusive use and take session slot if session is not open.
+func (s *cliSess) lock() error {
+	s.mu.Lock()
+
+	if s.held {
+		return nil
+	}
+
+	if err := takeSlot(s.slots, s.wait); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("cli session: %v", err)
+	}
+	s.held = true
+
+	return nil
+}
+
+// Unlock cli session. Frees session slot if session is closed.
+func (s *cliSess) unlock() {
+	s.dropSlot()
+	s.mu.Unlock()
+}
+
+// Take lease of pooled cli session. Blocks until lease is free or wait time is over
+func (s *cliSess) takeLease() error {
+	t := time.NewTimer(s.wait)
+	defer t.Stop()
+
+	select {
+	case s.lease <- struct{}{}:
+		return nil
+	case <-t.C:
+		return fmt.Errorf("cli session: session is held by other device object after %v", s.wait)
+	}
+}
+
+// Check if device object holds persistent cli session
+func (s *cliSess) ownedBy(d *device) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.owner == d
+}
+
+// Lock cli session for exclusive use of device object.
+// Waits until pooled session held by other device object is released.
+func (d *device) cliLock() error {
+	s := d.cliSession
+
+	leased := false
+	if s.lease != nil && !s.ownedBy(d) {
+		if err := s.takeLease(); err != nil {
+			return err
+		}
+		leased = true
+	}
+
+	if err := s.lock(); err != nil {
+		if leased {
+			<-s.lease
+		}
+		return err
+	}
+	s.leased = leased
+
+	return nil
+}
+
+// Unlock cli session of device object. Lease is kept if device object opened persistent session.
+func (d *device) cliUnlock() {
+	s := d.cliSession
+
+	release := s.leased && s.owner != d
+	s.leased = false
+	s.unlock()
+
+	if release {
+		<-s.lease
+	}
+}
+
+// Free session slot of closed cli session. Session must be locked.
+func (s *cliSess) dropSlot() {
+	if s.client == nil && s.held {
+		freeSlot(s.slots)
+		s.held = false
+	}
+}
+
+// Lease web session. Must be called before login.
+// Returns true if already authenticated pooled session can be reused.
+// Pooled session stays locked until leased() is called.
+func (s *webSess) lease() (bool, error) {
+	if !s.pooled {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	if s.client != nil {
+		s.refs++
+		s.mu.Unlock()
+		return true, nil
+	}
+
+	if err := takeSlot(s.slots, s.wait); err != nil {
+		s.mu.Unlock()
+		return false, fmt.Errorf("web session: %v", err)
+	}
+	s.held = true
+
+	return false, nil
+}
+
+// Finish web session lease after login attempt
+func (s *webSess) leased() {
+	if !s.pooled {
+		return
+	}
+
+	if s.client != nil {
+		s.refs = 1
+	} else {
+		freeSlot(s.slots)
+		s.held = false
+	}
+	s.mu.Unlock()
+}
+
+// Return web session lease. Must be called before logout.
+// Returns true if pooled session is still in use and logout must be skipped.
+// Pooled session stays locked until unleased() is called.
+func (s *webSess) unlease() bool {
+	if !s.pooled {
+		return false
+	}
+
+	s.mu.Lock()
+	if s.refs > 1 {
+		s.refs--
+		s.mu.Unlock()
+		return true
+	}
+
+	return false
+}
+
+// Finish web session lease return after logout attempt
+func (s *webSess) unleased() {
+	if !s.pooled {
+		return
+	}
+
+	s.refs = 0
+	if s.client == nil && s.held {
+		freeSlot(s.slots)
+		s.held = false
+	}
+	s.mu.Unlock()
+}
+
+// Serialize requests of pooled web session. Returns unlock function.
+func (s *webSess) serialize() func() {
+	if !s.pooled {
+		return func() {}
+	}
+
+	s.req.Lock()
+	return s.req.Unlock
+}
@@ -0,0 +1,569 @@
+package godevman
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aretaja/snmphelper"
+	"github.com/gosnmp/gosnmp"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	snmpTrapOid   = ".1.3.6.1.6.3.1.1.4.1.0"
+	sysUpTimeOid  = ".1.3.6.1.2.1.1.3.0"
+	linkDownOid   = ".1.3.6.1.6.3.1.1.5.3"
+	linkUpOid     = ".1.3.6.1.6.3.1.1.5.4"
+	ifIndexOid    = ".1.3.6.1.2.1.2.2.1.1."
+	genericTrapV1 = ".1.3.6.1.6.3.1.1.5."
+	// idle time after which trap source queue and its worker will be removed
+	trapQueueIdle = 10 * time.Minute
+)
+
+// SNMP trap receiver parameters
+type TrapParams struct {
+	// Listen address. Default "0.0.0.0:162"
+	Addr string
+	// Credentials for trap and inform decoding ([community] for v1/v2c or USM user for v3)
+	SnmpCred SnmpCred
+	// Parameters for sending device object initialization. Ip will be set to trap source ip.
+	// If DevParams.SnmpCred.User is empty, SnmpCred will be used
+	DevParams Dparams
+	// Resolve interface info of link traps via IfInfo. Default false
+	ResolveIf bool
+	// Event handler callback.
+	// If not defined, events will be delivered over channel returned by TrapReceiver.Events()
+	Handler func(*TrapEvent)
+	// Events channel buffer size. Default 100
+	BufSize int
+}
+
+// Decoded SNMP trap or inform
+type TrapEvent struct {
+	// Morphed device object of sending device (nil if device object initialization failed)
+	Device interface{}
+	// Variable bindings (oid - value)
+	Vars map[string]interface{}
+	// Decoded alarm info of alarm and clear events
+	Alarm *AlarmInfo
+	// Error of sending device identification or event decoding
+	Err error
+	// Receive time
+	Time time.Time
+	// Event type ("linkUp", "linkDown", "alarm", "clear", "other")
+	Type string
+	// Trap source ip
+	SrcIp string
+	// Trap oid (snmpTrapOID.0 or converted v1 trap oid)
+	TrapOid string
+	// Sending device sysName and sysObjectId
+	SysName, SysObjectId string
+	IfDescr, IfAlias     ValString
+	IfIdx                ValInt
+	UpTime               ValU64
+	Inform               bool
+	// Variable binding oids in received order
+	oids []string
+}
+
+// Trap sending device
+type trapDevice struct {
+	// morphed device object
+	morphed interface{}
+	dev     *device
+}
+
+// SNMP trap receiver
+type TrapReceiver struct {
+	listener *gosnmp.TrapListener
+	params   *TrapParams
+	events   chan *TrapEvent
+	// sending device objects cache
+	devices *cache.Cache
+	// ordered event queues per trap source
+	queues map[string]chan *TrapEvent
+	// idle time after which trap source queue will be removed
+	idle time.Duration
+	// serializes queue setup and close
+	mu     sync.Mutex
+	closed bool
+	// running queue workers
+	wg sync.WaitGroup
+}
+
+// Initialize new trap receiver
+func NewTrapReceiver(p *TrapParams) (*TrapReceiver, error) {
+	if p.SnmpCred.Ver == 0 {
+		return nil, fmt.Errorf("snmp version is required for trap receiver initialization")
+	}
+
+	// Use snmp session setup for USM parameters
+	session := snmphelper.Session{
+		Ver:      p.SnmpCred.Ver,
+		User:     p.SnmpCred.User,
+		Prot:     p.SnmpCred.Prot,
+		Pass:     p.SnmpCred.Pass,
+		Slevel:   p.SnmpCred.Slevel,
+		PrivProt: p.SnmpCred.PrivProt,
+		PrivPass: p.SnmpCred.PrivPass,
+	}
+
+	sess, err := session.New()
+	if err != nil {
+		return nil, fmt.Errorf("trap receiver snmp parameters setup failed - error: %v", err)
+	}
+
+	if p.Addr == "" {
+		p.Addr = "0.0.0.0:162"
+	}
+
+	if p.BufSize < 1 {
+		p.BufSize = 100
+	}
+
+	if p.DevParams.SnmpCred.User == "" {
+		p.DevParams.SnmpCred = p.SnmpCred
+	}
+
+	r := &TrapReceiver{
+		listener: gosnmp.NewTrapListener(),
+		params:   p,
+		devices:  cache.New(time.Hour, 10*time.Minute),
+		queues:   make(map[string]chan *TrapEvent),
+		idle:     trapQueueIdle,
+	}
+
+	if p.Handler == nil {
+		r.events = make(chan *TrapEvent, p.BufSize)
+	}
+
+	r.listener.Params = sess.Snmp
+	r.listener.OnNewTrap = r.onTrap
+
+	return r, nil
+}
+
+// Returns events channel (nil if TrapParams.Handler is defined)
+func (r *TrapReceiver) Events() <-chan *TrapEvent {
+	return r.events
+}
+
+// Start receiving traps. Blocks until Close is called or listen fails.
+func (r *TrapReceiver) Listen() error {
+	return r.listener.Listen(r.params.Addr)
+}
+
+// Stop receiving traps, wait for queued events and close events channel
+func (r *TrapReceiver) Close() {
+	r.listener.Close()
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	for _, q := range r.queues {
+		close(q)
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	if r.events != nil {
+		close(r.events)
+	}
+}
+
+// Trap listener callback. Copies needed packet data and queues it for decoding.
+func (r *TrapReceiver) onTrap(p *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	// Check community of v1/v2c traps
+	if r.params.SnmpCred.Ver < 3 && r.params.SnmpCred.User != "" && p.Community != r.params.SnmpCred.User {
+		log.Printf("warning: trap from %s with wrong community dropped\n", addr.IP.String())
+		return
+	}
+
+	e := &TrapEvent{
+		Vars:   make(map[string]interface{}),
+		Time:   time.Now(),
+		SrcIp:  addr.IP.String(),
+		Inform: p.PDUType == gosnmp.InformRequest,
+	}
+
+	for _, v := range p.Variables {
+		val := v.Value
+		if b, ok := v.Value.([]byte); ok {
+			val = string(b)
+		}
+
+		switch v.Name {
+		case snmpTrapOid:
+			if s, ok := val.(string); ok {
+				e.TrapOid = s
+			}
+		case sysUpTimeOid:
+			e.UpTime = ValU64{Value: gosnmp.ToBigInt(v.Value).Uint64(), IsSet: true}
+		default:
+			e.Vars[v.Name] = val
+			e.oids = append(e.oids, v.Name)
+		}
+	}
+
+	// v1 trap
+	if p.Version == gosnmp.Version1 {
+		if net.ParseIP(p.AgentAddress) != nil && p.AgentAddress != "0.0.0.0" {
+			e.SrcIp = p.AgentAddress
+		}
+
+		e.UpTime = ValU64{Value: uint64(p.Timestamp), IsSet: true}
+
+		// Convert to v2 trap oid (RFC 3584)
+		if p.GenericTrap < 6 {
+			e.TrapOid = fmt.Sprintf("%s%d", genericTrapV1, p.GenericTrap+1)
+		} else {
+			e.TrapOid = fmt.Sprintf("%s.0.%d", p.Enterprise, p.SpecificTrap)
+		}
+	}
+
+	r.enqueue(e)
+}
+
+// Add event to queue of trap source. Events of same source are processed in received order
+// by source worker. Events received after Close are dropped.
+func (r *TrapReceiver) enqueue(e *TrapEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	q, ok := r.queues[e.SrcIp]
+	if !ok {
+		q = make(chan *TrapEvent, r.params.BufSize)
+		r.queues[e.SrcIp] = q
+		r.wg.Add(1)
+		go r.worker(e.SrcIp, q)
+	}
+
+	select {
+	case q <- e:
+	default:
+		log.Printf("warning: trap queue of %s is full, event dropped\n", e.SrcIp)
+	}
+}
+
+// Process events of trap source queue until queue is closed.
+// Removes queue of trap source and exits if no events are received during idle time.
+func (r *TrapReceiver) worker(ip string, q chan *TrapEvent) {
+	defer r.wg.Done()
+
+	idle := time.NewTimer(r.idle)
+	defer idle.Stop()
+
+	for {
+		select {
+		case e, ok := <-q:
+			if !ok {
+				return
+			}
+			r.process(e)
+
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(r.idle)
+		case <-idle.C:
+			// Events are queued under lock, so empty queue can be removed safely.
+			// Closed queue will be drained and worker exits on close.
+			r.mu.Lock()
+			if !r.closed && len(q) == 0 {
+				delete(r.queues, ip)
+				r.mu.Unlock()
+				return
+			}
+			r.mu.Unlock()
+			idle.Reset(r.idle)
+		}
+	}
+}
+
+// Identify sending device, decode event and deliver it
+func (r *TrapReceiver) process(e *TrapEvent) {
+	if td, err := r.device(e.SrcIp); err != nil {
+		e.Err = err
+	} else {
+		e.Device = td.morphed
+		e.SysName = td.dev.sysName
+		e.SysObjectId = td.dev.sysObjectId
+	}
+
+	e.Type = "other"
+	switch {
+	case e.TrapOid == linkDownOid || e.TrapOid == linkUpOid:
+		e.decodeLink()
+		if r.params.ResolveIf && e.IfIdx.IsSet && e.Device != nil {
+			e.resolveIf()
+		}
+	default:
+		e.decodeAlarm()
+	}
+
+	if r.params.Handler != nil {
+		r.params.Handler(e)
+		return
+	}
+
+	select {
+	case r.events <- e:
+	default:
+		log.Printf("warning: trap receiver events channel is full, event from %s dropped\n", e.SrcIp)
+	}
+}
+
+// Returns sending device object from cache or initializes new one
+func (r *TrapReceiver) device(ip string) (*trapDevice, error) {
+	if x, found := r.devices.Get(ip); found {
+		return x.(*trapDevice), nil
+	}
+
+	p := r.params.DevParams
+	p.Ip = ip
+
+	d, err := NewDevice(&p)
+	if err != nil {
+		return nil, fmt.Errorf("sending device identification failed: %v", err)
+	}
+
+	td := &trapDevice{
+		morphed: d.Morph(),
+		dev:     d,
+	}
+	r.devices.Set(ip, td, cache.DefaultExpiration)
+
+	return td, nil
+}
+
+// Decode linkUp/linkDown trap
+func (e *TrapEvent) decodeLink() {
+	e.Type = "linkDown"
+	if e.TrapOid == linkUpOid {
+		e.Type = "linkUp"
+	}
+
+	for _, o := range e.oids {
+		if strings.HasPrefix(o, ifIndexOid) {
+			e.IfIdx = ValInt{Value: int(gosnmp.ToBigInt(e.Vars[o]).Int64()), IsSet: true}
+			break
+		}
+	}
+}
+
+// Resolve interface description and alias of link traps
+func (e *TrapEvent) resolveIf() {
+	d, ok := e.Device.(DevIfReader)
+	if !ok {
+		return
+	}
+
+	idx := fmt.Sprintf("%d", e.IfIdx.Value)
+	res, err := d.IfInfo([]string{"Descr", "Alias"}, idx)
+	if err != nil {
+		e.Err = fmt.Errorf("interface info resolve failed: %v", err)
+		return
+	}
+
+	if i, ok := res[idx]; ok {
+		e.IfDescr = i.Descr
+		e.IfAlias = i.Alias
+	}
+}
+
+// Decode vendor specific alarm traps
+func (e *TrapEvent) decodeAlarm() {
+	a := &AlarmInfo{
+		Severity: ValString{Value: "indeterminate", IsSet: true},
+	}
+
+	// Returns first string value of variable which oid has submitted prefix
+	strVar := func(prefix string) (string, bool) {
+		for _, o := range e.oids {
+			if s, ok := e.Vars[o].(string); ok && strings.HasPrefix(o, prefix) {
+				return strings.TrimSpace(s), true
+			}
+		}
+		return "", false
+	}
+
+	// Returns first integer value of variable which oid has submitted prefix
+	intVar := func(prefix string) (int64, bool) {
+		for _, o := range e.oids {
+			v := e.Vars[o]
+			if _, ok := v.(string); ok || !strings.HasPrefix(o, prefix) {
+				continue
+			}
+			return gosnmp.ToBigInt(v).Int64(), true
+		}
+		return 0, false
+	}
+
+	clear := false
+	switch {
+	// UPS-MIB
+	case strings.HasPrefix(e.TrapOid, ".1.3.6.1.2.1.33.2."):
+		a.Source = ValString{Value: "UPS", IsSet: true}
+		switch e.TrapOid {
+		case ".1.3.6.1.2.1.33.2.1":
+			a.Text = ValString{Value: "onBattery", IsSet: true}
+		case ".1.3.6.1.2.1.33.2.2":
+			a.Text = ValString{Value: "testCompleted", IsSet: true}
+			a.Severity.Value = "warning"
+		case ".1.3.6.1.2.1.33.2.3", ".1.3.6.1.2.1.33.2.4":
+			clear = e.TrapOid == ".1.3.6.1.2.1.33.2.4"
+			if s, ok := strVar(".1.3.6.1.2.1.33.1.6.2.1.2."); ok {
+				n, ok := upsWellKnownAlarms[s]
+				if !ok {
+					n = s
+				}
+				a.Text = ValString{Value: n, IsSet: true}
+			}
+		default:
+			return
+		}
+
+		if s, ok := upsAlarmSeverity[a.Text.Value]; ok {
+			a.Severity.Value = s
+		}
+	// CISCO-ENTITY-ALARM-MIB ceAlarmAsserted, ceAlarmCleared
+	case e.TrapOid == ".1.3.6.1.4.1.9.9.138.2.0.1" || e.TrapOid == ".1.3.6.1.4.1.9.9.138.2.0.2":
+		clear = e.TrapOid == ".1.3.6.1.4.1.9.9.138.2.0.2"
+		severity := map[int64]string{1: "critical", 2: "major", 3: "minor", 4: "warning"}
+
+		if i, ok := intVar(".1.3.6.1.4.1.9.9.138.1.3.3.1.3."); ok {
+			a.Source = ValString{Value: fmt.Sprintf("entity%d", i), IsSet: true}
+		}
+		if i, ok := intVar(".1.3.6.1.4.1.9.9.138.1.3.3.1.4."); ok {
+			a.Text = ValString{Value: fmt.Sprintf("alarm type %d", i), IsSet: true}
+		}
+		if i, ok := intVar(".1.3.6.1.4.1.9.9.138.1.3.3.1.5."); ok {
+			if s, ok := severity[i]; ok {
+				a.Severity.Value = s
+			}
+		}
+	// CISCO-SYSLOG-MIB clogMessageGenerated
+	case e.TrapOid == ".1.3.6.1.4.1.9.9.41.2.0.1":
+		// syslog severity: 1 - emergency ... 8 - debug
+		severity := map[int64]string{1: "critical", 2: "critical", 3: "critical", 4: "major", 5: "warning"}
+
+		if s, ok := strVar(".1.3.6.1.4.1.9.9.41.1.2.3.1.2."); ok {
+			a.Source = ValString{Value: s, IsSet: true}
+		}
+		if s, ok := strVar(".1.3.6.1.4.1.9.9.41.1.2.3.1.5."); ok {
+			a.Text = ValString{Value: s, IsSet: true}
+		}
+		if i, ok := intVar(".1.3.6.1.4.1.9.9.41.1.2.3.1.3."); ok {
+			s, ok := severity[i]
+			if !ok {
+				// Informational messages are not alarms
+				return
+			}
+			a.Severity.Value = s
+		}
+	// JUNIPER-CHASSIS-DEFINES-MIB jnxChassisTraps, jnxChassisOKTraps
+	case strings.HasPrefix(e.TrapOid, ".1.3.6.1.4.1.2636.4.1.") ||
+		strings.HasPrefix(e.TrapOid, ".1.3.6.1.4.1.2636.4.2."):
+		clear = strings.HasPrefix(e.TrapOid, ".1.3.6.1.4.1.2636.4.2.")
+		traps := map[string]string{
+			"1.1":  "powerSupplyFailure",
+			"1.2":  "fanFailure",
+			"1.3":  "overTemperature",
+			"1.4":  "redundancySwitchover",
+			"1.5":  "fruRemoval",
+			"1.6":  "fruInsertion",
+			"1.7":  "fruPowerOff",
+			"1.8":  "fruPowerOn",
+			"1.9":  "fruFailed",
+			"1.10": "fruOffline",
+			"1.11": "fruOnline",
+			"1.12": "fruCheck",
+			"2.1":  "powerSupplyOK",
+			"2.2":  "fanOK",
+			"2.3":  "temperatureOK",
+		}
+
+		n, ok := traps[strings.TrimPrefix(e.TrapOid, ".1.3.6.1.4.1.2636.4.")]
+		if !ok {
+			n = e.TrapOid
+		}
+
+		a.Text = ValString{Value: n, IsSet: true}
+		a.Severity.Value = "major"
+		if s, ok := strVar(".1.3.6.1.4.1.2636.3.1.6.1.6."); ok {
+			a.Source = ValString{Value: s, IsSet: true}
+		}
+	// ALARM-MIB alarmActiveState, alarmClearState (MINI-LINK)
+	case e.TrapOid == ".1.3.6.1.2.1.118.0.2" || e.TrapOid == ".1.3.6.1.2.1.118.0.3":
+		clear = e.TrapOid == ".1.3.6.1.2.1.118.0.3"
+		if s, ok := strVar(alarmActiveResourceId + "."); ok {
+			a.Source = ValString{Value: s, IsSet: true}
+		}
+		if s, ok := strVar(alarmActiveDescr + "."); ok {
+			a.Text = ValString{Value: s, IsSet: true}
+		}
+		if s, ok := strVar(alarmActiveModelPtr + "."); ok {
+			// Model pointer last index is alarmModelState
+			p := strings.Split(s, ".")
+			var st int64
+			if _, err := fmt.Sscan(p[len(p)-1], &st); err == nil {
+				if sev, ok := ituPerceivedSeverity[st]; ok && st > 1 {
+					a.Severity.Value = sev
+				}
+			}
+		}
+	// MINI-LINK enterprise traps
+	case strings.HasPrefix(e.TrapOid, ".1.3.6.1.4.1.193."):
+		a.Source = ValString{Value: "MINI-LINK", IsSet: true}
+		if s, ok := strVar(".1.3.6.1.4.1.193."); ok {
+			a.Text = ValString{Value: s, IsSet: true}
+		}
+	// Eltek traps
+	case strings.HasPrefix(e.TrapOid, ".1.3.6.1.4.1.12148."):
+		a.Source = ValString{Value: "Eltek", IsSet: true}
+		if s, ok := strVar(".1.3.6.1.4.1.12148."); ok {
+			a.Text = ValString{Value: s, IsSet: true}
+		}
+		if i, ok := intVar(".1.3.6.1.4.1.12148."); ok {
+			if s, ok := eltekSp2Status[i]; ok {
+				switch s {
+				case "normal":
+					clear = true
+				case "error", "critical":
+					a.Severity.Value = "critical"
+				case "majorAlarm", "minorAndMajor", "majorLow", "majorHigh":
+					a.Severity.Value = "major"
+				case "minorAlarm", "minorLow", "minorHigh":
+					a.Severity.Value = "minor"
+				case "warning":
+					a.Severity.Value = "warning"
+				}
+			}
+		}
+	default:
+		return
+	}
+
+	if !a.Text.IsSet {
+		a.Text = ValString{Value: e.TrapOid, IsSet: true}
+	}
+
+	a.setRaised(e.Time)
+
+	e.Alarm = a
+	e.Type = "alarm"
+	if clear {
+		e.Type = "clear"
+	}
+}